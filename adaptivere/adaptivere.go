@@ -0,0 +1,343 @@
+// Package adaptivere implements a thread-safe cache for [Go's standard regex
+// package] that complies with the [recache.Cache] interface and picks its
+// cache replacement policy at run time.
+//
+// Next to the live policy, the cache runs a small shadow cache for every
+// candidate policy, which only holds metadata for a sample of the keys looked
+// up. At the end of every window of sampled lookups, the live policy is
+// switched to the candidate whose shadow had the best hit ratio, if it beat
+// the live one by a clear margin.
+//
+// [Go's standard regex package]: https://godocs.io/regexp
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+package adaptivere
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/mockingjayre"
+)
+
+const (
+	// DefaultWindow is the default number of sampled lookups after which the
+	// shadows are compared and the live policy may be switched.
+	DefaultWindow int = 1_000
+
+	// _maxShadowSize is the largest capacity of a shadow cache. Caches larger
+	// than that only sample a fraction of their keys into the shadows.
+	_maxShadowSize int = 512
+
+	// _switchMargin is the fraction of the window by which the hits of a
+	// candidate must beat those of the live policy to replace it, which keeps
+	// the cache from flapping between policies that perform about the same.
+	_switchMargin float64 = 0.02
+)
+
+// Candidate is a cache replacement policy the cache can switch to.
+type Candidate struct {
+	// New returns a new, empty instance of the policy.
+	New func() recache.Policy
+
+	// Name identifies the policy, as reported by Active.
+	Name string
+}
+
+// DefaultCandidates returns the candidates used by New: LRU from the lrure
+// package and LFU from the mockingjayre package, starting with LRU.
+func DefaultCandidates() []Candidate {
+	return []Candidate{
+		{Name: "lrure", New: func() recache.Policy { return lrure.NewPolicy() }},
+		{Name: "mockingjayre", New: func() recache.Policy { return mockingjayre.NewPolicy() }},
+	}
+}
+
+// Report is the performance of a candidate's shadow over the last complete
+// window.
+type Report struct {
+	// Name is the name of the candidate.
+	Name string
+
+	// HitRatio is the fraction of sampled lookups that hit the shadow.
+	HitRatio float64
+
+	// Active reports whether the candidate is the live policy.
+	Active bool
+}
+
+// Cache is a thread-safe regex cache that adapts its cache replacement policy
+// to the workload.
+type Cache struct {
+	*recache.Store
+
+	// policy is the adaptive policy used by the store.
+	policy *Policy
+}
+
+// Compile-time check to ensure Cache implements the recache.Cache interface.
+var _ recache.Cache = (*Cache)(nil)
+
+// New returns a new adaptive cache with the given capacity and options, such
+// as [recache.WithLogger], choosing between the [DefaultCandidates].
+//
+// If capacity is less than 1, [recache.DefaultCapacity] is used instead.
+//
+// [recache.DefaultCapacity]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#DefaultCapacity
+func New(capacity int, opts ...recache.StoreOption) *Cache {
+	return NewWithCandidates(capacity, DefaultCandidates(), DefaultWindow, opts...)
+}
+
+// NewWithCandidates is like New, but chooses between the given candidates,
+// starting with the first one, and compares them every window sampled
+// lookups. If candidates is empty, DefaultCandidates is used instead, and if
+// window is less than 1, DefaultWindow is used instead.
+func NewWithCandidates(capacity int, candidates []Candidate, window int, opts ...recache.StoreOption) *Cache {
+	p := NewPolicy(candidates, window)
+
+	return &Cache{
+		Store:  recache.NewStore(capacity, p, opts...),
+		policy: p,
+	}
+}
+
+// Active returns the name of the live cache replacement policy.
+func (c *Cache) Active() string {
+	return c.policy.Active()
+}
+
+// Switches returns the number of times the live policy was switched.
+func (c *Cache) Switches() uint64 {
+	return c.policy.Switches()
+}
+
+// Reports returns the performance of every candidate over the last complete
+// window, or nil if no window completed yet.
+func (c *Cache) Reports() []Report {
+	return c.policy.Reports()
+}
+
+// Policy is a cache replacement policy that delegates to the best performing
+// of several candidates.
+type Policy struct {
+	// live is the instance of the active candidate that makes the actual
+	// eviction decisions.
+	live recache.Policy
+
+	// entries is a map of the keys of tracked entries to their entries, which
+	// are handed to a new live policy when switching.
+	entries map[string]*recache.Entry
+
+	// reports holds the reports of the last complete window.
+	reports atomic.Pointer[[]Report]
+
+	// candidates are the policies to choose from.
+	candidates []Candidate
+
+	// shadows simulate each candidate, in the same order.
+	shadows []*shadow
+
+	// switches counts the number of times the live policy was switched.
+	switches atomic.Uint64
+
+	// active is the index of the live candidate.
+	active atomic.Int32
+
+	// threshold is the largest key hash sampled into the shadows.
+	threshold uint64
+
+	// capacity is the capacity of the cache.
+	capacity int
+
+	// window is the number of sampled lookups between comparisons.
+	window int
+
+	// sampled is the number of sampled lookups in the current window.
+	sampled int
+}
+
+// Compile-time check to ensure Policy implements the recache.Policy interface.
+var _ recache.Policy = (*Policy)(nil)
+
+// NewPolicy returns a new adaptive policy choosing between the given
+// candidates, starting with the first one, and comparing them every window
+// sampled lookups. If candidates is empty, DefaultCandidates is used instead,
+// and if window is less than 1, DefaultWindow is used instead.
+func NewPolicy(candidates []Candidate, window int) *Policy {
+	if len(candidates) == 0 {
+		candidates = DefaultCandidates()
+	}
+
+	if window < 1 {
+		window = DefaultWindow
+	}
+
+	p := &Policy{
+		live:       candidates[0].New(),
+		entries:    make(map[string]*recache.Entry),
+		candidates: candidates,
+		window:     window,
+	}
+
+	p.Resize(recache.DefaultCapacity)
+
+	return p
+}
+
+// Active returns the name of the live candidate. It is safe for concurrent
+// use.
+func (p *Policy) Active() string {
+	return p.candidates[p.active.Load()].Name
+}
+
+// Switches returns the number of times the live policy was switched. It is
+// safe for concurrent use.
+func (p *Policy) Switches() uint64 {
+	return p.switches.Load()
+}
+
+// Reports returns the performance of every candidate over the last complete
+// window, or nil if no window completed yet. It is safe for concurrent use.
+func (p *Policy) Reports() []Report {
+	reports := p.reports.Load()
+	if reports == nil {
+		return nil
+	}
+
+	return append([]Report(nil), *reports...)
+}
+
+// Access records the lookup with the live policy and, if the key is sampled,
+// with the shadows.
+func (p *Policy) Access(entry *recache.Entry) {
+	p.live.Access(entry)
+	p.observe(entry)
+}
+
+// Insert starts tracking the given entry with the live policy and, if the key
+// is sampled, records the lookup that missed with the shadows.
+func (p *Policy) Insert(entry *recache.Entry) {
+	p.entries[entry.Key()] = entry
+
+	p.live.Insert(entry)
+	p.observe(entry)
+}
+
+// Victim returns the key of the entry the live policy would evict.
+func (p *Policy) Victim() (string, bool) {
+	return p.live.Victim()
+}
+
+// Remove stops tracking the entry with the given key.
+func (p *Policy) Remove(key string) {
+	delete(p.entries, key)
+
+	p.live.Remove(key)
+}
+
+// Resize informs the live policy of the new capacity and starts new shadows
+// sized for it.
+func (p *Policy) Resize(capacity int) {
+	p.capacity = capacity
+	p.live.Resize(capacity)
+
+	size := capacity
+	p.threshold = ^uint64(0)
+
+	if capacity > _maxShadowSize {
+		size = _maxShadowSize
+		p.threshold = uint64(float64(size) / float64(capacity) * (1 << 63) * 2)
+	}
+
+	p.shadows = make([]*shadow, len(p.candidates))
+
+	for i, candidate := range p.candidates {
+		p.shadows[i] = newShadow(candidate.New(), size)
+	}
+
+	p.sampled = 0
+}
+
+// observe records the lookup of the given entry with the shadows if its key
+// is sampled, and compares the shadows at the end of a window.
+func (p *Policy) observe(entry *recache.Entry) {
+	if hash(entry.Key()) > p.threshold {
+		return
+	}
+
+	for _, s := range p.shadows {
+		s.lookup(entry.Key(), entry.Flag())
+	}
+
+	p.sampled++
+
+	if p.sampled >= p.window {
+		p.compare()
+	}
+}
+
+// compare publishes the reports of the window that just ended and switches
+// the live policy if a candidate clearly beat it.
+func (p *Policy) compare() {
+	var (
+		active  = int(p.active.Load())
+		best    = active
+		reports = make([]Report, len(p.shadows))
+	)
+
+	for i, s := range p.shadows {
+		if s.hits > p.shadows[best].hits {
+			best = i
+		}
+
+		reports[i] = Report{
+			Name:     p.candidates[i].Name,
+			HitRatio: float64(s.hits) / float64(p.sampled),
+		}
+	}
+
+	margin := uint64(float64(p.sampled) * _switchMargin)
+
+	if best != active && p.shadows[best].hits > p.shadows[active].hits+margin {
+		p.switchTo(best)
+
+		active = best
+	}
+
+	reports[active].Active = true
+	p.reports.Store(&reports)
+
+	for _, s := range p.shadows {
+		s.hits = 0
+	}
+
+	p.sampled = 0
+}
+
+// switchTo replaces the live policy with a new instance of the candidate at
+// the given index, handing it the tracked entries from least to most recently
+// used so that recency-based policies start out in the right order.
+func (p *Policy) switchTo(i int) {
+	entries := make([]*recache.Entry, 0, len(p.entries))
+
+	for _, entry := range p.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Accessed().Before(entries[b].Accessed())
+	})
+
+	live := p.candidates[i].New()
+	live.Resize(p.capacity)
+
+	for _, entry := range entries {
+		live.Insert(entry)
+	}
+
+	p.live = live
+
+	p.active.Store(int32(i)) //nolint:gosec // there are never that many candidates
+	p.switches.Add(1)
+}
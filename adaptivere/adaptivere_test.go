@@ -0,0 +1,138 @@
+package adaptivere_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/adaptivere"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/mockingjayre"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+)
+
+func get(t *testing.T, cache recache.Cache, pattern string) {
+	t.Helper()
+
+	if _, err := cache.Get(context.Background(), pattern, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get(%q) error = %v", pattern, err)
+	}
+}
+
+// cycle looks up a few hot patterns twice each, followed by a scan of patterns
+// that are only looked up once, which flushes a small LRU cache every time.
+func cycle(t *testing.T, cache recache.Cache, i int) {
+	t.Helper()
+
+	for hot := 0; hot < 5; hot++ {
+		get(t, cache, fmt.Sprintf(`^hot%d$`, hot))
+		get(t, cache, fmt.Sprintf(`^hot%d$`, hot))
+	}
+
+	for j := 0; j < 10; j++ {
+		get(t, cache, fmt.Sprintf(`^scan%d-%d$`, i, j))
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Switches to LFU under scans", func(t *testing.T) {
+		t.Parallel()
+
+		cache := adaptivere.NewWithCandidates(10, adaptivere.DefaultCandidates(), 100)
+
+		if got := cache.Active(); got != "lrure" {
+			t.Fatalf("Active() = %q, want %q", got, "lrure")
+		}
+
+		if reports := cache.Reports(); reports != nil {
+			t.Errorf("Reports() before the first window = %v, want nil", reports)
+		}
+
+		for i := 0; i < 100; i++ {
+			cycle(t, cache, i)
+		}
+
+		if got := cache.Active(); got != "mockingjayre" {
+			t.Errorf("Active() = %q, want %q", got, "mockingjayre")
+		}
+
+		if got := cache.Switches(); got != 1 {
+			t.Errorf("Switches() = %d, want 1", got)
+		}
+
+		reports := cache.Reports()
+
+		if len(reports) != 2 || !reports[1].Active || reports[1].HitRatio <= reports[0].HitRatio {
+			t.Errorf("Reports() = %+v, want mockingjayre active and ahead", reports)
+		}
+	})
+
+	t.Run("Switches to LRU when the working set moves", func(t *testing.T) {
+		t.Parallel()
+
+		candidates := []adaptivere.Candidate{
+			{Name: "mockingjayre", New: func() recache.Policy { return mockingjayre.NewPolicy() }},
+			{Name: "lrure", New: func() recache.Policy { return lrure.NewPolicy() }},
+		}
+
+		cache := adaptivere.NewWithCandidates(10, candidates, 100)
+
+		// The first working set becomes very popular, then the workload moves
+		// on to working sets that each stay for a while. The stale
+		// frequencies keep the first set in an LFU cache.
+		for i := 0; i < 500; i++ {
+			get(t, cache, fmt.Sprintf(`^old%d$`, i%8))
+		}
+
+		for phase := 0; phase < 20; phase++ {
+			for i := 0; i < 100; i++ {
+				get(t, cache, fmt.Sprintf(`^new%d-%d$`, phase, i%8))
+			}
+		}
+
+		if got := cache.Active(); got != "lrure" {
+			t.Errorf("Active() = %q, want %q", got, "lrure")
+		}
+	})
+
+	t.Run("Keeps entries when switching", func(t *testing.T) {
+		t.Parallel()
+
+		cache := adaptivere.NewWithCandidates(10, adaptivere.DefaultCandidates(), 100)
+
+		for i := 0; cache.Switches() == 0; i++ {
+			if i == 100 {
+				t.Fatal("the live policy was never switched")
+			}
+
+			cycle(t, cache, i)
+		}
+
+		if got := cache.Size(); got != 10 {
+			t.Fatalf("Size() after switching = %d, want 10", got)
+		}
+
+		// Every entry cached before the switch is still served from the cache
+		// by the new live policy.
+		for _, entry := range cache.Entries() {
+			before := cache.Stats().Hits
+
+			get(t, cache, entry.Pattern())
+
+			if cache.Stats().Hits == before {
+				t.Errorf("Get(%q) missed after switching", entry.Pattern())
+			}
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	recachetest.Run(t, func(capacity int) recache.Cache {
+		return adaptivere.New(capacity)
+	})
+}
@@ -0,0 +1,75 @@
+package adaptivere
+
+import (
+	"hash/fnv"
+	"regexp"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// _ghost is the regular expression held by every shadow entry. Shadows only
+// need the metadata of entries, so they all share this one instead of
+// compiling their patterns.
+var _ghost = regexp.MustCompile(`^$`)
+
+// shadow simulates a cache using a candidate policy, holding only metadata.
+type shadow struct {
+	// policy is the simulated policy.
+	policy recache.Policy
+
+	// entries is a map of the keys in the shadow to their ghost entries.
+	entries map[string]*recache.Entry
+
+	// capacity is the maximum number of keys in the shadow.
+	capacity int
+
+	// hits is the number of lookups that hit the shadow in the current
+	// window.
+	hits uint64
+}
+
+// newShadow returns a new, empty shadow of the given capacity.
+func newShadow(p recache.Policy, capacity int) *shadow {
+	p.Resize(capacity)
+
+	return &shadow{
+		policy:   p,
+		entries:  make(map[string]*recache.Entry, capacity),
+		capacity: capacity,
+	}
+}
+
+// lookup simulates a lookup of the given key.
+func (s *shadow) lookup(key string, flag recache.Flag) {
+	if entry, ok := s.entries[key]; ok {
+		s.policy.Access(entry)
+		entry.Touch()
+
+		s.hits++
+
+		return
+	}
+
+	for len(s.entries) >= s.capacity {
+		victim, ok := s.policy.Victim()
+		if !ok {
+			break
+		}
+
+		delete(s.entries, victim)
+		s.policy.Remove(victim)
+	}
+
+	entry := recache.NewEntryWithFlag(key, key, flag, _ghost)
+
+	s.entries[key] = entry
+	s.policy.Insert(entry)
+}
+
+// hash returns the 64-bit FNV-1a hash of the given key.
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return h.Sum64()
+}
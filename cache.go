@@ -45,6 +45,10 @@ func (f Flag) String() string {
 type Cache interface {
 	// Get returns a compiled regular expression from the cache given a pattern
 	// and an optional flag.
+	//
+	// The returned regular expression must not be shared with the cache's
+	// internal state; implementations must hand out a copy so that calling
+	// Longest on it does not change matching for other callers.
 	Get(ctx context.Context, pattern string, flag Flag) (*regexp.Regexp, error)
 
 	// SetCapacity sets the maximum number of regular expressions that can be
@@ -62,3 +66,31 @@ type Cache interface {
 	// Clear removes all regular expressions from the cache.
 	Clear()
 }
+
+// EntryLister is implemented by caches that can list the entries they hold.
+type EntryLister interface {
+	// Entries returns a snapshot of the entries currently stored in the cache,
+	// in no particular order.
+	Entries() []*Entry
+}
+
+// Deleter is implemented by caches that can remove a single regular
+// expression.
+type Deleter interface {
+	// Delete removes the regular expression compiled from the given pattern
+	// and flag from the cache, and reports whether it was present.
+	Delete(pattern string, flag Flag) bool
+}
+
+// Pinner is implemented by caches that can pin regular expressions, which
+// are never evicted until unpinned.
+type Pinner interface {
+	// Pin compiles the given pattern with the given flag if needed and keeps
+	// it in the cache until Unpin is called. Pinned regular expressions count
+	// against the cache's capacity.
+	Pin(ctx context.Context, pattern string, flag Flag) error
+
+	// Unpin makes a pinned regular expression evictable again, and reports
+	// whether it was pinned.
+	Unpin(pattern string, flag Flag) bool
+}
@@ -0,0 +1,28 @@
+package recache
+
+import "time"
+
+// Clock tells the time. Caches read the time through a Clock rather than
+// calling time.Now directly, so that time-based behavior can be tested
+// deterministically by injecting a fake clock, such as the one in the
+// [recachetest] package.
+//
+// [recachetest]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go/recachetest
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// systemClock is a Clock that reads the system time.
+type systemClock struct{}
+
+// Now returns the current system time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock returns a Clock that reads the system time. It is the default
+// clock of every cache in this module.
+func SystemClock() Clock {
+	return systemClock{}
+}
@@ -0,0 +1,150 @@
+// Command recache-gen generates typed accessors for a set of regular
+// expressions, backed by recache.Lazy, and fails if any of them is invalid, so
+// typos are caught when generating code instead of at run time.
+//
+// Usage:
+//
+//	recache-gen [-in file] [-out file] [-pkg name]
+//
+// It is meant to be run by go generate:
+//
+//	//go:generate go run git.sr.ht/~jamesponddotco/recache-go/cmd/recache-gen -in patterns.txt
+//
+// The patterns file holds one pattern per line, in the form
+//
+//	name [flag] = pattern
+//
+// where flag is the string representation of a recache.Flag, such as POSIX,
+// and may be omitted for the default flag. See the recachegen package for the
+// full format. A pattern named email generates an Email function returning a
+// *recache.LazyRegexp, and a Warm function compiles every pattern up front.
+//
+// The output is only written if every pattern is valid, and the package name
+// defaults to the one go generate runs in.
+//
+// Run with -help for the full list of flags.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"git.sr.ht/~jamesponddotco/recache-go/recachegen"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "recache-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the command line, reads the patterns, and writes the generated
+// code.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("recache-gen", flag.ContinueOnError)
+
+	var (
+		in  = fs.String("in", "patterns.txt", "read the patterns from `file`; - reads from standard input")
+		out = fs.String("out", "", "write the generated code to `file`; - writes to standard output; defaults to the input file with a _gen.go suffix")
+		pkg = fs.String("pkg", os.Getenv("GOPACKAGE"), "`name` of the generated package; defaults to $GOPACKAGE, set by go generate")
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	patterns, err := readPatterns(*in, stdin)
+	if err != nil {
+		return err
+	}
+
+	cfg := recachegen.Config{
+		Package:  *pkg,
+		Patterns: patterns,
+	}
+
+	if *in != "-" {
+		cfg.Source = filepath.Base(*in)
+	}
+
+	var buf bytes.Buffer
+
+	if err = recachegen.Generate(&buf, cfg); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	path := *out
+	if path == "" {
+		if *in == "-" {
+			path = "-"
+		} else {
+			path = strings.TrimSuffix(*in, filepath.Ext(*in)) + "_gen.go"
+		}
+	}
+
+	if path == "-" {
+		if _, err = stdout.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		return nil
+	}
+
+	if err = os.WriteFile(path, buf.Bytes(), 0o644); err != nil { //nolint:gosec // generated source files are world-readable
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// readPatterns reads the patterns file at path, or standard input if path is
+// "-".
+func readPatterns(path string, stdin io.Reader) ([]recachegen.Pattern, error) {
+	if path == "-" {
+		patterns, err := recachegen.Parse(stdin)
+		if err != nil {
+			return nil, withPath("<stdin>", err)
+		}
+
+		return patterns, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+	defer f.Close()
+
+	patterns, err := recachegen.Parse(f)
+	if err != nil {
+		return nil, withPath(path, err)
+	}
+
+	return patterns, nil
+}
+
+// withPath prefixes every error joined in err with the path of the patterns
+// file, so each invalid line is reported on its own line with its location.
+func withPath(path string, err error) error {
+	var joined interface{ Unwrap() []error }
+
+	if !errors.As(err, &joined) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	errs := joined.Unwrap()
+	prefixed := make([]error, 0, len(errs))
+
+	for _, e := range errs {
+		prefixed = append(prefixed, fmt.Errorf("%s: %w", path, e))
+	}
+
+	return errors.Join(prefixed...)
+}
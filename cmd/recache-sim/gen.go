@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+// ErrUnknownGenerator is returned when a generator name is not recognized.
+const ErrUnknownGenerator xerrors.Error = "unknown generator"
+
+const (
+	// GeneratorZipf draws patterns from a Zipf distribution, so a few hot
+	// patterns account for most lookups, as in most real programs.
+	GeneratorZipf = "zipf"
+
+	// GeneratorScan interleaves Zipf-distributed lookups with scans of patterns
+	// that are each looked up only once, which tests scan resistance.
+	GeneratorScan = "scan"
+
+	// GeneratorLoop cycles through every pattern in order, which defeats LRU
+	// once there are more patterns than the cache can hold.
+	GeneratorLoop = "loop"
+)
+
+// _scanPeriod is the number of lookups in each phase of a scan trace. Every
+// fifth phase is a scan.
+const _scanPeriod int = 100
+
+// GeneratorConfig configures a synthetic trace.
+type GeneratorConfig struct {
+	// Accesses is the number of lookups in the trace.
+	Accesses int
+
+	// Patterns is the number of distinct patterns in the trace, not counting
+	// the one-off patterns of scans.
+	Patterns int
+
+	// Skew is the s parameter of the Zipf distribution, greater than 1.
+	Skew float64
+
+	// Seed seeds the random number generator.
+	Seed int64
+}
+
+// Generate returns a synthetic trace built by the named generator.
+func Generate(name string, cfg GeneratorConfig) ([]Access, error) {
+	if cfg.Patterns < 1 {
+		cfg.Patterns = 1
+	}
+
+	if cfg.Skew <= 1 {
+		cfg.Skew = 1.1
+	}
+
+	var (
+		trace = make([]Access, 0, cfg.Accesses)
+		rng   = rand.New(rand.NewSource(cfg.Seed)) //nolint:gosec // traces need not be unpredictable
+		zipf  = rand.NewZipf(rng, cfg.Skew, 1, uint64(cfg.Patterns-1))
+	)
+
+	switch name {
+	case GeneratorZipf:
+		for i := 0; i < cfg.Accesses; i++ {
+			trace = append(trace, synthetic(int(zipf.Uint64())))
+		}
+	case GeneratorScan:
+		scanned := cfg.Patterns
+
+		for i := 0; i < cfg.Accesses; i++ {
+			if (i/_scanPeriod)%5 == 4 {
+				trace = append(trace, synthetic(scanned))
+				scanned++
+
+				continue
+			}
+
+			trace = append(trace, synthetic(int(zipf.Uint64())))
+		}
+	case GeneratorLoop:
+		for i := 0; i < cfg.Accesses; i++ {
+			trace = append(trace, synthetic(i%cfg.Patterns))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownGenerator, name)
+	}
+
+	return trace, nil
+}
+
+// synthetic returns the i-th synthetic pattern, shaped like a typical route
+// pattern so that compiling it costs about as much as a real one.
+func synthetic(i int) Access {
+	return Access{
+		Pattern: fmt.Sprintf(`^/api/v%d/(?P<resource>[a-z]+)/(?P<id>[0-9]+)(?:/[a-z-]+)*$`, i),
+		Flag:    recache.DefaultFlag,
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	cfg := GeneratorConfig{
+		Accesses: 1_000,
+		Patterns: 10,
+		Skew:     1.2,
+		Seed:     42,
+	}
+
+	tests := []struct {
+		name    string
+		give    string
+		wantErr error
+		check   func(t *testing.T, trace []Access)
+	}{
+		{
+			name: "Zipf",
+			give: GeneratorZipf,
+			check: func(t *testing.T, trace []Access) {
+				t.Helper()
+
+				counts := make(map[Access]int)
+
+				for _, access := range trace {
+					counts[access]++
+				}
+
+				if len(counts) > cfg.Patterns {
+					t.Errorf("trace has %d distinct patterns, want at most %d", len(counts), cfg.Patterns)
+				}
+
+				// The first pattern is the most likely one.
+				for access, count := range counts {
+					if count > counts[synthetic(0)] {
+						t.Errorf("pattern %q drawn %d times, more than the first pattern's %d", access.Pattern, count, counts[synthetic(0)])
+					}
+				}
+			},
+		},
+		{
+			name: "Scan",
+			give: GeneratorScan,
+			check: func(t *testing.T, trace []Access) {
+				t.Helper()
+
+				// Every fifth phase scans one-off patterns, numbered after the
+				// Zipf-distributed ones.
+				for i, want := 4*_scanPeriod, cfg.Patterns; i < 5*_scanPeriod; i, want = i+1, want+1 {
+					if trace[i] != synthetic(want) {
+						t.Fatalf("trace[%d] = %q, want %q", i, trace[i].Pattern, synthetic(want).Pattern)
+					}
+				}
+
+				for i := 0; i < 4*_scanPeriod; i++ {
+					if trace[i] == synthetic(cfg.Patterns) {
+						t.Fatalf("trace[%d] is a scanned pattern outside of a scan phase", i)
+					}
+				}
+			},
+		},
+		{
+			name: "Loop",
+			give: GeneratorLoop,
+			check: func(t *testing.T, trace []Access) {
+				t.Helper()
+
+				for i, access := range trace {
+					if access != synthetic(i%cfg.Patterns) {
+						t.Fatalf("trace[%d] = %q, want %q", i, access.Pattern, synthetic(i%cfg.Patterns).Pattern)
+					}
+				}
+			},
+		},
+		{
+			name:    "Unknown generator",
+			give:    "random",
+			wantErr: ErrUnknownGenerator,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			trace, err := Generate(tt.give, cfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if len(trace) != cfg.Accesses {
+				t.Fatalf("len(Generate()) = %d, want %d", len(trace), cfg.Accesses)
+			}
+
+			again, err := Generate(tt.give, cfg)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(trace, again) {
+				t.Errorf("Generate() with the same seed returned different traces")
+			}
+
+			tt.check(t, trace)
+		})
+	}
+}
+
+func TestGenerateDefaults(t *testing.T) {
+	t.Parallel()
+
+	// Too few patterns and a skew the Zipf distribution rejects are replaced
+	// by defaults instead of panicking.
+	trace, err := Generate(GeneratorZipf, GeneratorConfig{Accesses: 10})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for i, access := range trace {
+		if access != synthetic(0) {
+			t.Errorf("trace[%d] = %q, want the only pattern %q", i, access.Pattern, synthetic(0).Pattern)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCapacities(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		give    string
+		want    []int
+		wantErr error
+	}{
+		{
+			name: "Single",
+			give: "16",
+			want: []int{16},
+		},
+		{
+			name: "List with spaces",
+			give: "16, 64 ,256",
+			want: []int{16, 64, 256},
+		},
+		{
+			name:    "Zero",
+			give:    "16,0",
+			wantErr: ErrInvalidCapacity,
+		},
+		{
+			name:    "Not a number",
+			give:    "lots",
+			wantErr: ErrInvalidCapacity,
+		},
+		{
+			name:    "Empty",
+			give:    "",
+			wantErr: ErrInvalidCapacity,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseCapacities(tt.give)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseCapacities() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCapacities() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		args      []string
+		stdin     string
+		wantLines int
+		wantErr   bool
+	}{
+		{
+			name:      "Synthetic trace",
+			args:      []string{"-gen", "loop", "-n", "100", "-patterns", "4", "-capacities", "2,8", "-policies", "lrure", "-format", "csv"},
+			wantLines: 3,
+		},
+		{
+			name:      "Trace from standard input",
+			args:      []string{"-trace", "-", "-capacities", "1", "-format", "csv"},
+			stdin:     `{"pattern": "a"}` + "\n" + `{"pattern": "a"}` + "\n",
+			wantLines: 1 + len(Policies()),
+		},
+		{
+			name:    "Invalid capacities",
+			args:    []string{"-capacities", "0"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown generator",
+			args:    []string{"-gen", "random"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout strings.Builder
+
+			err := run(tt.args, strings.NewReader(tt.stdin), &stdout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			if got := strings.Count(stdout.String(), "\n"); !tt.wantErr && got != tt.wantLines {
+				t.Errorf("run() wrote %d lines, want %d:\n%s", got, tt.wantLines, stdout.String())
+			}
+		})
+	}
+}
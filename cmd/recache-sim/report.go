@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+// ErrUnknownFormat is returned when an output format is not recognized.
+const ErrUnknownFormat xerrors.Error = "unknown format"
+
+const (
+	// FormatTable writes results as an aligned, human-readable table.
+	FormatTable = "table"
+
+	// FormatCSV writes results as comma-separated values, with a header.
+	FormatCSV = "csv"
+)
+
+// WriteResults writes the results to w in the given format.
+func WriteResults(w io.Writer, format string, results []Result) error {
+	switch format {
+	case FormatTable:
+		return writeTable(w, results)
+	case FormatCSV:
+		return writeCSV(w, results)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// writeTable writes the results as an aligned table.
+func writeTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(tw, "POLICY\tCAPACITY\tACCESSES\tHITS\tMISSES\tHIT RATIO\tCOMPILE SAVED\tEVICTIONS\tERRORS\t")
+
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.2f%%\t%s\t%d\t%d\t\n",
+			r.Policy, r.Capacity, r.Accesses, r.Hits, r.Misses, r.HitRatio()*100, r.Saved, r.Evictions, r.Errors)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// writeCSV writes the results as comma-separated values.
+func writeCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{
+		"policy", "capacity", "accesses", "hits", "misses", "hit_ratio",
+		"compile_saved_seconds", "evictions", "errors",
+	}
+
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	for _, r := range results {
+		row := []string{
+			r.Policy,
+			strconv.Itoa(r.Capacity),
+			strconv.Itoa(r.Accesses),
+			strconv.FormatUint(r.Hits, 10),
+			strconv.FormatUint(r.Misses, 10),
+			strconv.FormatFloat(r.HitRatio(), 'f', 6, 64),
+			strconv.FormatFloat(r.Saved.Seconds(), 'f', 6, 64),
+			strconv.FormatUint(r.Evictions, 10),
+			strconv.Itoa(r.Errors),
+		}
+
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
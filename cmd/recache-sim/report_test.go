@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteResults(t *testing.T) {
+	t.Parallel()
+
+	results := []Result{
+		{
+			Policy:    "lrure",
+			Capacity:  16,
+			Accesses:  10,
+			Hits:      7,
+			Misses:    3,
+			Evictions: 1,
+			Errors:    1,
+			Saved:     1500 * time.Microsecond,
+		},
+		{
+			Policy:   "twoqre",
+			Capacity: 256,
+			Accesses: 10,
+			Hits:     9,
+			Misses:   1,
+			Saved:    2 * time.Millisecond,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		give    string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Table",
+			give: FormatTable,
+			want: "" +
+				"  POLICY  CAPACITY  ACCESSES  HITS  MISSES  HIT RATIO  COMPILE SAVED  EVICTIONS  ERRORS\n" +
+				"   lrure        16        10     7       3     70.00%          1.5ms          1       1\n" +
+				"  twoqre       256        10     9       1     90.00%            2ms          0       0\n",
+		},
+		{
+			name: "CSV",
+			give: FormatCSV,
+			want: "" +
+				"policy,capacity,accesses,hits,misses,hit_ratio,compile_saved_seconds,evictions,errors\n" +
+				"lrure,16,10,7,3,0.700000,0.001500,1,1\n" +
+				"twoqre,256,10,9,1,0.900000,0.002000,0,0\n",
+		},
+		{
+			name:    "Unknown format",
+			give:    "xml",
+			wantErr: ErrUnknownFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var b strings.Builder
+
+			err := WriteResults(&b, tt.give, results)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WriteResults() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := trimLines(b.String()); got != tt.want {
+				t.Errorf("WriteResults() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// trimLines returns s with the trailing spaces of every line removed, which
+// tabwriter leaves after the last column.
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/adaptivere"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/mockingjayre"
+	"git.sr.ht/~jamesponddotco/recache-go/s3fifore"
+	"git.sr.ht/~jamesponddotco/recache-go/sampledre"
+	"git.sr.ht/~jamesponddotco/recache-go/twoqre"
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+const (
+	// ErrUnknownPolicy is returned when a policy name is not recognized.
+	ErrUnknownPolicy xerrors.Error = "unknown policy"
+
+	// ErrInvalidCapacity is returned when a capacity is not a positive integer.
+	ErrInvalidCapacity xerrors.Error = "invalid capacity"
+)
+
+// _compileSamples is the number of times each distinct pattern is compiled to
+// measure its cost. The fastest run is kept.
+const _compileSamples int = 3
+
+// Policy is a cache replacement policy that can be simulated.
+type Policy struct {
+	// New returns a new, empty policy.
+	New func() recache.Policy
+
+	// Name is the name of the package implementing the policy.
+	Name string
+}
+
+// Policies returns every cache replacement policy in recache, in the order
+// they are reported.
+func Policies() []Policy {
+	return []Policy{
+		{Name: "lrure", New: func() recache.Policy { return lrure.NewPolicy() }},
+		{Name: "mockingjayre", New: func() recache.Policy { return mockingjayre.NewPolicy() }},
+		{Name: "sampledre-lru", New: func() recache.Policy { return sampledre.NewPolicy(sampledre.DefaultSamples, sampledre.ModeLRU) }},
+		{Name: "sampledre-lfu", New: func() recache.Policy { return sampledre.NewPolicy(sampledre.DefaultSamples, sampledre.ModeLFU) }},
+		{Name: "s3fifore", New: func() recache.Policy { return s3fifore.NewPolicy() }},
+		{Name: "twoqre", New: func() recache.Policy { return twoqre.NewPolicy(twoqre.DefaultKin, twoqre.DefaultKout) }},
+		{Name: "adaptivere", New: newAdaptivePolicy},
+	}
+}
+
+// newAdaptivePolicy returns an adaptivere policy with the default candidates
+// and window.
+func newAdaptivePolicy() recache.Policy {
+	return adaptivere.NewPolicy(adaptivere.DefaultCandidates(), adaptivere.DefaultWindow)
+}
+
+// selectPolicies returns the policies named in the comma-separated list, or
+// every policy if the list is empty.
+func selectPolicies(s string) ([]Policy, error) {
+	all := Policies()
+
+	if s == "" {
+		return all, nil
+	}
+
+	var selected []Policy
+
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+
+		for _, p := range all {
+			if p.Name == name {
+				selected = append(selected, p)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownPolicy, name)
+		}
+	}
+
+	return selected, nil
+}
+
+// Result is the outcome of replaying a trace against a policy at a capacity.
+type Result struct {
+	// Policy is the name of the simulated policy.
+	Policy string
+
+	// Capacity is the capacity of the simulated cache.
+	Capacity int
+
+	// Accesses is the number of lookups in the trace.
+	Accesses int
+
+	// Hits is the number of lookups served from the cache.
+	Hits uint64
+
+	// Misses is the number of lookups that compiled their pattern.
+	Misses uint64
+
+	// Evictions is the number of entries evicted from the cache.
+	Evictions uint64
+
+	// Errors is the number of lookups whose pattern failed to compile.
+	Errors int
+
+	// Saved is the compile time that cache hits avoided.
+	Saved time.Duration
+}
+
+// HitRatio returns the fraction of lookups served from the cache.
+func (r Result) HitRatio() float64 {
+	if r.Hits+r.Misses == 0 {
+		return 0
+	}
+
+	return float64(r.Hits) / float64(r.Hits+r.Misses)
+}
+
+// Simulate replays the trace against every policy at every capacity. Lookups
+// cost the compile time given in costs, if any, and the compile time measured
+// for their pattern otherwise.
+func Simulate(trace []Access, costs map[Access]time.Duration, policies []Policy, capacities []int) []Result {
+	costs = compileCosts(trace, costs)
+	results := make([]Result, 0, len(policies)*len(capacities))
+
+	for _, p := range policies {
+		for _, capacity := range capacities {
+			results = append(results, simulate(trace, costs, p, capacity))
+		}
+	}
+
+	return results
+}
+
+// simulate replays the trace against a single policy at a single capacity.
+func simulate(trace []Access, costs map[Access]time.Duration, p Policy, capacity int) Result {
+	var (
+		ctx    = context.Background()
+		store  = recache.NewStore(capacity, p.New())
+		result = Result{
+			Policy:   p.Name,
+			Capacity: capacity,
+			Accesses: len(trace),
+		}
+		hits uint64
+	)
+
+	for _, access := range trace {
+		if err := get(ctx, store, access); err != nil {
+			result.Errors++
+
+			continue
+		}
+
+		// A lookup that raised the hit counter was served from the cache and
+		// saved a compile.
+		if stats := store.Stats(); stats.Hits > hits {
+			hits = stats.Hits
+			result.Saved += costs[access]
+		}
+	}
+
+	stats := store.Stats()
+
+	result.Hits = stats.Hits
+	result.Misses = stats.Misses
+	result.Evictions = stats.Evictions
+
+	return result
+}
+
+// get looks the access up in the cache, turning the panics of patterns
+// compiled with recache.FlagMust into errors.
+func get(ctx context.Context, cache recache.Cache, access Access) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r) //nolint:goerr113 // the panic value is not an error
+		}
+	}()
+
+	_, err = cache.Get(ctx, access.Pattern, access.Flag)
+
+	return err
+}
+
+// compileCosts measures how long it takes to compile each distinct access in
+// the trace that is not already in known. Accesses that fail to compile cost
+// nothing.
+func compileCosts(trace []Access, known map[Access]time.Duration) map[Access]time.Duration {
+	costs := make(map[Access]time.Duration, len(known))
+
+	for access, cost := range known {
+		costs[access] = cost
+	}
+
+	for _, access := range trace {
+		if _, ok := costs[access]; ok {
+			continue
+		}
+
+		var best time.Duration
+
+		for i := 0; i < _compileSamples; i++ {
+			start := time.Now()
+
+			if err := compile(access); err != nil {
+				best = 0
+
+				break
+			}
+
+			if d := time.Since(start); i == 0 || d < best {
+				best = d
+			}
+		}
+
+		costs[access] = best
+	}
+
+	return costs
+}
+
+// compile compiles the access's pattern, turning panics into errors.
+func compile(access Access) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r) //nolint:goerr113 // the panic value is not an error
+		}
+	}()
+
+	_, err = recache.Compile(access.Pattern, access.Flag)
+
+	return err
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+func TestSimulate(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a = Access{Pattern: `a`}
+		b = Access{Pattern: `b`}
+		c = Access{Pattern: `c`}
+
+		// Invalid patterns are counted as errors, with or without FlagMust.
+		invalid     = Access{Pattern: `(`}
+		invalidMust = Access{Pattern: `(`, Flag: recache.FlagMust}
+
+		costs = map[Access]time.Duration{
+			a: time.Millisecond,
+			b: 2 * time.Millisecond,
+			c: 4 * time.Millisecond,
+		}
+	)
+
+	// Every policy agrees on these traces: with a single slot, each pattern
+	// evicts the previous one, and with room for everything, nothing is
+	// evicted.
+	tests := []struct {
+		name     string
+		trace    []Access
+		capacity int
+		want     Result
+	}{
+		{
+			name:     "Single slot",
+			trace:    []Access{a, a, b, b, invalid, c, c, a},
+			capacity: 1,
+			want: Result{
+				Accesses:  8,
+				Hits:      3,
+				Misses:    5,
+				Evictions: 3,
+				Errors:    1,
+				Saved:     7 * time.Millisecond,
+			},
+		},
+		{
+			name:     "Everything fits",
+			trace:    []Access{a, b, c, a, b, c, invalidMust, c},
+			capacity: 4,
+			want: Result{
+				Accesses: 8,
+				Hits:     4,
+				Misses:   4,
+				Errors:   1,
+				Saved:    11 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			results := Simulate(tt.trace, costs, Policies(), []int{tt.capacity})
+
+			if len(results) != len(Policies()) {
+				t.Fatalf("len(Simulate()) = %d, want one result per policy", len(results))
+			}
+
+			for i, got := range results {
+				want := tt.want
+				want.Policy = Policies()[i].Name
+				want.Capacity = tt.capacity
+
+				if got != want {
+					t.Errorf("Simulate() = %+v, want %+v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateMeasuresCosts(t *testing.T) {
+	t.Parallel()
+
+	trace := []Access{{Pattern: `^[a-z]+@[a-z]+\.com$`}, {Pattern: `^[a-z]+@[a-z]+\.com$`}}
+
+	policies, err := selectPolicies("lrure")
+	if err != nil {
+		t.Fatalf("selectPolicies() error = %v", err)
+	}
+
+	// Without known costs, the compile time saved by the hit is measured.
+	results := Simulate(trace, nil, policies, []int{1})
+	if len(results) != 1 || results[0].Hits != 1 || results[0].Saved <= 0 {
+		t.Errorf("Simulate() = %+v, want one hit saving a measured compile time", results)
+	}
+}
+
+func TestSelectPolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		give    string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "Every policy",
+			give: "",
+			want: policyNames(Policies()),
+		},
+		{
+			name: "Selected policies in the given order",
+			give: "twoqre, lrure",
+			want: []string{"twoqre", "lrure"},
+		},
+		{
+			name:    "Unknown policy",
+			give:    "lrure,mru",
+			wantErr: ErrUnknownPolicy,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := selectPolicies(tt.give)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("selectPolicies() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if names := policyNames(got); fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("selectPolicies() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultHitRatio(t *testing.T) {
+	t.Parallel()
+
+	if got := (Result{}).HitRatio(); got != 0 {
+		t.Errorf("HitRatio() without lookups = %v, want 0", got)
+	}
+
+	if got := (Result{Hits: 3, Misses: 1}).HitRatio(); got != 0.75 {
+		t.Errorf("HitRatio() = %v, want 0.75", got)
+	}
+}
+
+// policyNames returns the names of the given policies.
+func policyNames(policies []Policy) []string {
+	names := make([]string, 0, len(policies))
+
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/recorder"
+)
+
+// Access is a single lookup in a trace.
+type Access struct {
+	// Pattern is the regular expression looked up.
+	Pattern string
+
+	// Flag is the flag the pattern is compiled with.
+	Flag recache.Flag
+}
+
+// ReadTrace reads a trace in the format written by the recorder package, and
+// returns its lookups along with the compile times recorded for them.
+//
+// Records that identify their pattern by key only are replayed with the key
+// as a literal pattern, which behaves the same in the cache, and cost the
+// compile time recorded on their misses.
+func ReadTrace(reader *recorder.Reader) ([]Access, map[Access]time.Duration, error) {
+	var (
+		trace []Access
+		costs = make(map[Access]time.Duration)
+	)
+
+	for {
+		rec, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return trace, costs, nil
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w", err)
+		}
+
+		access := Access{
+			Pattern: rec.Pattern,
+			Flag:    rec.Flag,
+		}
+
+		if access.Pattern == "" {
+			access.Pattern = regexp.QuoteMeta(rec.Key)
+		}
+
+		if rec.Outcome == recorder.OutcomeMiss && rec.Compile > 0 {
+			costs[access] = rec.Compile
+		}
+
+		trace = append(trace, access)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recorder"
+)
+
+func TestReadTrace(t *testing.T) {
+	t.Parallel()
+
+	want := []Access{
+		{Pattern: `^a+$`, Flag: recache.DefaultFlag},
+		{Pattern: `b|c`, Flag: recache.FlagPOSIX},
+		{Pattern: `^a+$`, Flag: recache.DefaultFlag},
+	}
+
+	tests := []struct {
+		name string
+		opts []recorder.Option
+		want []Access
+	}{
+		{
+			name: "Patterns",
+			opts: []recorder.Option{recorder.WithPatterns()},
+			want: want,
+		},
+		{
+			// Records without patterns are replayed with their key as a
+			// literal pattern.
+			name: "Keys only",
+			want: []Access{
+				{Pattern: regexp.QuoteMeta(recache.Key(`^a+$`, recache.DefaultFlag)), Flag: recache.DefaultFlag},
+				{Pattern: regexp.QuoteMeta(recache.Key(`b|c`, recache.FlagPOSIX)), Flag: recache.FlagPOSIX},
+				{Pattern: regexp.QuoteMeta(recache.Key(`^a+$`, recache.DefaultFlag)), Flag: recache.DefaultFlag},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				buf bytes.Buffer
+				rec = recorder.New(lrure.New(recache.DefaultCapacity), &buf, tt.opts...)
+			)
+
+			for _, access := range want {
+				if _, err := rec.Get(context.Background(), access.Pattern, access.Flag); err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+			}
+
+			if err := rec.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			trace, costs, err := ReadTrace(recorder.NewReader(&buf))
+			if err != nil {
+				t.Fatalf("ReadTrace() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(trace, tt.want) {
+				t.Errorf("ReadTrace() = %v, want %v", trace, tt.want)
+			}
+
+			// Only the two misses recorded a compile time.
+			if len(costs) != 2 || costs[tt.want[0]] <= 0 || costs[tt.want[1]] <= 0 {
+				t.Errorf("ReadTrace() costs = %v, want positive costs for the two misses", costs)
+			}
+		})
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		give string
+	}{
+		{
+			name: "Malformed JSON",
+			give: `{"pattern": "a"` + "\n",
+		},
+		{
+			name: "Unknown flag",
+			give: `{"pattern": "a", "flag": "Sideways"}` + "\n",
+		},
+		{
+			name: "Unknown outcome",
+			give: `{"pattern": "a", "outcome": "maybe"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, _, err := ReadTrace(recorder.NewReader(strings.NewReader(tt.give))); err == nil {
+				t.Errorf("ReadTrace() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestReadTraceHandWritten(t *testing.T) {
+	t.Parallel()
+
+	const give = `{"pattern": "^[a-z]+$", "flag": "POSIX"}
+{"pattern": "x", "outcome": "miss", "compile_ns": 1500}
+{"pattern": "x", "outcome": "hit"}
+`
+
+	trace, costs, err := ReadTrace(recorder.NewReader(strings.NewReader(give)))
+	if err != nil {
+		t.Fatalf("ReadTrace() error = %v", err)
+	}
+
+	want := []Access{
+		{Pattern: `^[a-z]+$`, Flag: recache.FlagPOSIX},
+		{Pattern: `x`},
+		{Pattern: `x`},
+	}
+
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("ReadTrace() = %v, want %v", trace, want)
+	}
+
+	if len(costs) != 1 || costs[want[1]] != 1500 {
+		t.Errorf("ReadTrace() costs = %v, want 1.5µs for x", costs)
+	}
+}
@@ -0,0 +1,586 @@
+// Package cowre implements a thread-safe, read-optimized cache for [Go's
+// standard regex package] that complies with the [recache.Cache] interface.
+//
+// Hits are served from an immutable snapshot of the cache reached through an
+// atomic pointer, so they take no lock at all. Hits are counted in counters
+// spread over several cache lines, and only about one hit in
+// [DefaultSampleRate] records the access on its entry, so goroutines serving
+// hits for the same pattern rarely write to the same memory. Misses are compiled and queued,
+// and the snapshot is rebuilt copy-on-write once enough of them are queued or
+// a short interval has passed, but never more than once per interval. Queued
+// regular expressions are served under a lock until the next rebuild.
+//
+// Rebuilding copies the whole snapshot, so the cache suits services that look
+// up a mostly static set of patterns that fits in its capacity. Once the cache
+// is full, a miss between two rebuilds is compiled but not cached. Rebuilds
+// that need room evict the least frequently used entries, as far as the
+// sampled hits tell.
+//
+// [Go's standard regex package]: https://godocs.io/regexp
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+package cowre
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/internal/xregexp"
+)
+
+const (
+	// DefaultBatchSize is the default number of queued regular expressions
+	// that triggers a rebuild of the snapshot.
+	DefaultBatchSize int = 16
+
+	// DefaultRebuildInterval is the default minimum time between two rebuilds
+	// of the snapshot, which is also the longest a queued regular expression
+	// waits to be published.
+	DefaultRebuildInterval time.Duration = 10 * time.Millisecond
+
+	// DefaultSampleRate is the default number of hits, on average, per hit
+	// that records the access on its entry.
+	DefaultSampleRate int = 16
+)
+
+const (
+	// _hitShards is the number of counters hits are spread over. It is a
+	// power of two.
+	_hitShards int = 16
+
+	// _cacheLineSize is the size hit counters are padded to, so that
+	// counting a hit in one does not slow down hits counted in the others.
+	_cacheLineSize int = 64
+)
+
+// Option configures a Cache.
+type Option func(*Cache)
+
+// WithClock sets the clock the cache reads the time from for the timestamps of
+// its entries. Rebuilds are still timed with the system clock. If not set, or
+// nil, the system clock is used.
+func WithClock(clock recache.Clock) Option {
+	return func(c *Cache) {
+		if clock == nil {
+			clock = recache.SystemClock()
+		}
+
+		c.clock = clock
+	}
+}
+
+// WithSampleRate sets the number of hits, on average, per hit that records the
+// access on its entry, which updates its frequency and access time. Sampling
+// keeps goroutines that serve hits for the same pattern from writing to the
+// same entry, at the cost of frequencies that count sampled hits only. A rate
+// of 1 records every hit. If not set, or less than 1, [DefaultSampleRate] is
+// used.
+func WithSampleRate(rate int) Option {
+	return func(c *Cache) {
+		if rate < 1 {
+			rate = DefaultSampleRate
+		}
+
+		c.sampleRate = uint64(rate)
+	}
+}
+
+// Cache is a thread-safe regex cache whose hits take no lock.
+type Cache struct {
+	// snapshot holds the immutable map of keys to slots hits are served
+	// from.
+	snapshot atomic.Pointer[map[string]slot]
+
+	// pending is a map of the keys compiled since the last rebuild to their
+	// slots.
+	pending map[string]slot
+
+	// clock is the clock the timestamps of entries are read from.
+	clock recache.Clock
+
+	// rebuilt is the time of the last rebuild.
+	rebuilt time.Time
+
+	// counters keeps track of the cache's activity, except for hits.
+	counters recache.Counters
+
+	// hits counts hits, spread over several counters picked at random.
+	hits [_hitShards]hitCounter
+
+	// rebuilds counts the number of times the snapshot was rebuilt.
+	rebuilds atomic.Uint64
+
+	// capacity is the maximum number of items the cache can hold.
+	capacity int
+
+	// batchSize is the number of pending entries that triggers a rebuild.
+	batchSize int
+
+	// sampleRate is the number of hits, on average, per hit recorded on its
+	// entry.
+	sampleRate uint64
+
+	// interval is the minimum time between two rebuilds.
+	interval time.Duration
+
+	// scheduled reports whether a rebuild is scheduled to publish the pending
+	// entries.
+	scheduled bool
+
+	// mu is a mutex that protects access to everything but the snapshot's
+	// contents.
+	mu sync.Mutex
+}
+
+// Compile-time check to ensure Cache implements the recache.Cache,
+// recache.StatsReporter, recache.EntryLister, and recache.Deleter interfaces.
+var (
+	_ recache.Cache         = (*Cache)(nil)
+	_ recache.StatsReporter = (*Cache)(nil)
+	_ recache.EntryLister   = (*Cache)(nil)
+	_ recache.Deleter       = (*Cache)(nil)
+)
+
+// slot is a cached regular expression with the entry that tracks its usage.
+type slot struct {
+	// entry is the entry of the regular expression, only touched by sampled
+	// hits.
+	entry *recache.Entry
+
+	// regex is the compiled regular expression, which is never handed out
+	// directly.
+	regex *regexp.Regexp
+}
+
+// hitCounter is a hit counter padded to a cache line.
+type hitCounter struct {
+	n atomic.Uint64
+
+	_ [_cacheLineSize - 8]byte
+}
+
+// New returns a new copy-on-write cache with the given capacity and options,
+// using [DefaultBatchSize] and [DefaultRebuildInterval].
+//
+// If capacity is less than 1, [recache.DefaultCapacity] is used instead.
+//
+// [recache.DefaultCapacity]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#DefaultCapacity
+func New(capacity int, opts ...Option) *Cache {
+	return NewWithBatching(capacity, DefaultBatchSize, DefaultRebuildInterval, opts...)
+}
+
+// NewWithBatching is like New, but rebuilds the snapshot once batchSize
+// regular expressions are queued, at most once per interval. If batchSize is
+// less than 1, DefaultBatchSize is used instead, and if interval is less than
+// 0, DefaultRebuildInterval is used instead. An interval of 0 does not limit
+// the rate of rebuilds.
+func NewWithBatching(capacity, batchSize int, interval time.Duration, opts ...Option) *Cache {
+	if capacity < 1 {
+		capacity = recache.DefaultCapacity
+	}
+
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+
+	if interval < 0 {
+		interval = DefaultRebuildInterval
+	}
+
+	c := &Cache{
+		pending:    make(map[string]slot, batchSize),
+		clock:      recache.SystemClock(),
+		capacity:   capacity,
+		batchSize:  batchSize,
+		sampleRate: uint64(DefaultSampleRate),
+		interval:   interval,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.snapshot.Store(&map[string]slot{})
+
+	return c
+}
+
+// Get returns a compiled regular expression from the cache given a pattern and
+// an optional flag.
+//
+// If the regular expression is not in the cache, it is compiled outside the
+// lock and queued for the next rebuild.
+func (c *Cache) Get(ctx context.Context, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
+	key := recache.Key(pattern, flag)
+
+	cached, ok := (*c.snapshot.Load())[key]
+	if !ok {
+		cached, ok = c.loadPending(key)
+	}
+
+	if ok {
+		c.hit(cached)
+		recache.Observe(ctx, true, 0)
+
+		return xregexp.Copy(cached.regex), nil
+	}
+
+	c.counters.Miss()
+
+	start := time.Now()
+	regex, err := recache.Compile(pattern, flag)
+	duration := time.Since(start)
+
+	c.counters.Compiled(duration, err)
+	recache.Observe(ctx, false, duration)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return c.insert(key, pattern, flag, regex)
+}
+
+// Flush rebuilds the snapshot at once if any regular expressions are queued,
+// regardless of the batch size and rebuild interval, so they are served
+// without a lock from then on. It is useful after warming up the cache.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pending) > 0 {
+		c.rebuild()
+	}
+}
+
+// Rebuilds returns the number of times the snapshot was rebuilt.
+func (c *Cache) Rebuilds() uint64 {
+	return c.rebuilds.Load()
+}
+
+// SetCapacity sets the maximum number of regular expressions that can be
+// stored in the cache, rebuilding the snapshot at once to evict entries if the
+// cache holds more than that.
+func (c *Cache) SetCapacity(capacity int) error {
+	if capacity < 1 {
+		return fmt.Errorf("%w", recache.ErrInvalidCapacity)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+
+	if c.size() > capacity {
+		c.rebuild()
+	}
+
+	return nil
+}
+
+// Capacity returns the maximum number of regular expressions that can be
+// stored in the cache.
+func (c *Cache) Capacity() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.capacity
+}
+
+// Size returns the number of regular expressions currently stored in the
+// cache, including the ones queued for the next rebuild.
+func (c *Cache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size()
+}
+
+// Entries returns a snapshot of the entries currently stored in the cache,
+// including the ones queued for the next rebuild, in no particular order.
+func (c *Cache) Entries() []*recache.Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		snapshot = *c.snapshot.Load()
+		entries  = make([]*recache.Entry, 0, len(snapshot)+len(c.pending))
+	)
+
+	for _, cached := range snapshot {
+		entries = append(entries, cached.entry)
+	}
+
+	for _, cached := range c.pending {
+		entries = append(entries, cached.entry)
+	}
+
+	return entries
+}
+
+// Delete removes the regular expression compiled from the given pattern and
+// flag from the cache, and reports whether it was present. Deleting a
+// published regular expression copies the snapshot at once.
+func (c *Cache) Delete(pattern string, flag recache.Flag) bool {
+	key := recache.Key(pattern, flag)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.pending[key]; ok {
+		delete(c.pending, key)
+
+		return true
+	}
+
+	snapshot := *c.snapshot.Load()
+
+	if _, ok := snapshot[key]; !ok {
+		return false
+	}
+
+	next := make(map[string]slot, len(snapshot)-1)
+
+	for k, cached := range snapshot {
+		if k != key {
+			next[k] = cached
+		}
+	}
+
+	c.snapshot.Store(&next)
+
+	return true
+}
+
+// Stats returns a snapshot of the cache's activity.
+func (c *Cache) Stats() recache.Stats {
+	stats := c.counters.Stats()
+
+	for i := range c.hits {
+		stats.Hits += c.hits[i].n.Load()
+	}
+
+	return stats
+}
+
+// Clear removes all regular expressions from the cache, including the ones
+// queued for the next rebuild.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.snapshot.Store(&map[string]slot{})
+	clear(c.pending)
+}
+
+// hit counts a hit on the given slot in one of the hit counters, picked at
+// random, and records the access on its entry if the hit is sampled.
+func (c *Cache) hit(cached slot) {
+	r := rand.Uint64() //nolint:gosec // sampling need not be unpredictable
+
+	c.hits[r%uint64(_hitShards)].n.Add(1)
+
+	if (r/uint64(_hitShards))%c.sampleRate == 0 {
+		cached.entry.Touch()
+	}
+}
+
+// loadPending returns the slot stored under the given key if it was queued
+// since the last rebuild, or published by a rebuild that happened after the
+// caller read the snapshot.
+func (c *Cache) loadPending(key string) (slot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := (*c.snapshot.Load())[key]; ok {
+		return cached, true
+	}
+
+	cached, ok := c.pending[key]
+
+	return cached, ok
+}
+
+// insert queues a freshly compiled regular expression for the next rebuild,
+// rebuilding the snapshot at once if the batch is complete or the cache is
+// full and the rebuild interval has passed. It returns the regular expression
+// to hand out.
+func (c *Cache) insert(key, pattern string, flag recache.Flag, regex *regexp.Regexp) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another goroutine may have added the same pattern while it was being
+	// compiled.
+	cached, ok := (*c.snapshot.Load())[key]
+	if !ok {
+		cached, ok = c.pending[key]
+	}
+
+	if ok {
+		cached.entry.Touch()
+
+		return xregexp.Copy(cached.regex), nil
+	}
+
+	var (
+		full    = c.size() >= c.capacity
+		elapsed = time.Since(c.rebuilt) >= c.interval
+	)
+
+	// Making room means copying the snapshot, so a full cache waits for the
+	// rebuild interval before caching anything else.
+	if full && !elapsed {
+		return regex, nil
+	}
+
+	// Cache a copy, so callers changing the one handed out, with Longest,
+	// cannot change the one served to others.
+	cached = slot{regex: xregexp.Copy(regex)}
+	cached.entry = recache.NewEntryWithClock(key, pattern, flag, cached.regex, c.clock)
+
+	c.pending[key] = cached
+
+	if elapsed && (full || len(c.pending) >= c.batchSize) {
+		c.rebuild()
+
+		return regex, nil
+	}
+
+	c.schedule()
+
+	return regex, nil
+}
+
+// schedule arranges for the pending entries to be published once the rebuild
+// interval has passed, unless a rebuild is already scheduled. The caller must
+// hold the lock.
+func (c *Cache) schedule() {
+	if c.scheduled {
+		return
+	}
+
+	c.scheduled = true
+
+	time.AfterFunc(c.interval-time.Since(c.rebuilt), c.publish)
+}
+
+// publish rebuilds the snapshot if entries are pending, or schedules itself
+// again if another rebuild happened too recently. It is called by the timer
+// set by schedule.
+func (c *Cache) publish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scheduled = false
+
+	if len(c.pending) == 0 {
+		return
+	}
+
+	if time.Since(c.rebuilt) < c.interval {
+		c.schedule()
+
+		return
+	}
+
+	c.rebuild()
+}
+
+// rebuild publishes a new snapshot holding the current snapshot's entries and
+// the pending ones, evicting the least frequently used entries if they do not
+// fit in the cache's capacity. Entries are evicted from the current snapshot
+// before pending ones, which were just compiled. The caller must hold the
+// lock.
+func (c *Cache) rebuild() {
+	var (
+		snapshot = *c.snapshot.Load()
+		excess   = len(snapshot) + len(c.pending) - c.capacity
+		evicted  = make(map[string]struct{}, max(excess, 0))
+	)
+
+	if excess > 0 {
+		keys := victims(snapshot, excess)
+
+		if len(keys) < excess {
+			keys = append(keys, victims(c.pending, excess-len(keys))...)
+		}
+
+		for _, key := range keys {
+			evicted[key] = struct{}{}
+		}
+
+		c.counters.Evict(len(keys))
+	}
+
+	next := make(map[string]slot, len(snapshot)+len(c.pending)-len(evicted))
+
+	for key, cached := range snapshot {
+		if _, ok := evicted[key]; !ok {
+			next[key] = cached
+		}
+	}
+
+	for key, cached := range c.pending {
+		if _, ok := evicted[key]; !ok {
+			next[key] = cached
+		}
+	}
+
+	c.snapshot.Store(&next)
+	clear(c.pending)
+
+	c.rebuilt = time.Now()
+	c.rebuilds.Add(1)
+}
+
+// size returns the number of entries in the snapshot and pending. The caller
+// must hold the lock.
+func (c *Cache) size() int {
+	return len(*c.snapshot.Load()) + len(c.pending)
+}
+
+// victims returns the keys of up to n of the given entries to evict, least
+// frequently used first, breaking ties by least recently used.
+func victims(entries map[string]slot, n int) []string {
+	type candidate struct {
+		accessed  time.Time
+		key       string
+		frequency uint64
+	}
+
+	// Hits keep updating the entries while they are sorted, so sort a copy
+	// of their counters instead.
+	candidates := make([]candidate, 0, len(entries))
+
+	for key, cached := range entries {
+		candidates = append(candidates, candidate{
+			accessed:  cached.entry.Accessed(),
+			key:       key,
+			frequency: cached.entry.Frequency(),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+
+		if a.frequency != b.frequency {
+			return a.frequency < b.frequency
+		}
+
+		return a.accessed.Before(b.accessed)
+	})
+
+	keys := make([]string, 0, min(n, len(candidates)))
+
+	for _, c := range candidates[:min(n, len(candidates))] {
+		keys = append(keys, c.key)
+	}
+
+	return keys
+}
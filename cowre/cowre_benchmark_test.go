@@ -0,0 +1,34 @@
+package cowre_test
+
+import (
+	"context"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/cowre"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/s3fifore"
+)
+
+// BenchmarkParallelHits measures lookups that always hit the cache from many
+// goroutines at once, where the lock taken on hits matters most.
+func BenchmarkParallelHits(b *testing.B) {
+	ctx := context.Background()
+
+	cow := cowre.New(recache.DefaultCapacity)
+
+	for _, pattern := range recachetest.BenchmarkPatterns {
+		if _, err := cow.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	cow.Flush()
+
+	recachetest.BenchmarkParallelHits(b,
+		recachetest.NamedCache{Name: "cowre", Cache: cow},
+		recachetest.NamedCache{Name: "lrure", Cache: lrure.New(recache.DefaultCapacity)},
+		recachetest.NamedCache{Name: "s3fifore", Cache: s3fifore.New(recache.DefaultCapacity)},
+	)
+}
@@ -0,0 +1,228 @@
+package cowre_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/cowre"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+)
+
+// get looks up the given pattern, failing the test on error.
+func get(t *testing.T, cache *cowre.Cache, pattern string) {
+	t.Helper()
+
+	if _, err := cache.Get(context.Background(), pattern, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get(%q) error = %v", pattern, err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Rebuilds once a batch is queued", func(t *testing.T) {
+		t.Parallel()
+
+		cache := cowre.NewWithBatching(10, 3, 0)
+
+		get(t, cache, "a")
+		get(t, cache, "b")
+
+		if got := cache.Rebuilds(); got != 0 {
+			t.Errorf("Rebuilds() before the batch is complete = %d, want 0", got)
+		}
+
+		// Queued patterns are served before the rebuild.
+		get(t, cache, "a")
+
+		get(t, cache, "c")
+
+		if got := cache.Rebuilds(); got != 1 {
+			t.Errorf("Rebuilds() after the batch is complete = %d, want 1", got)
+		}
+
+		if got := cache.Stats(); got.Hits != 1 || got.Misses != 3 {
+			t.Errorf("Stats() = %d hits, %d misses, want 1, 3", got.Hits, got.Misses)
+		}
+
+		if got := cache.Size(); got != 3 {
+			t.Errorf("Size() = %d, want 3", got)
+		}
+	})
+
+	t.Run("Rebuilds are rate-limited", func(t *testing.T) {
+		t.Parallel()
+
+		cache := cowre.NewWithBatching(10, 1, time.Hour)
+
+		for _, pattern := range []string{"a", "b", "c"} {
+			get(t, cache, pattern)
+		}
+
+		// The first rebuild is not limited, the others wait for the interval.
+		if got := cache.Rebuilds(); got != 1 {
+			t.Errorf("Rebuilds() = %d, want 1", got)
+		}
+
+		if got := cache.Size(); got != 3 {
+			t.Errorf("Size() = %d, want 3", got)
+		}
+
+		cache.Flush()
+
+		if got := cache.Rebuilds(); got != 2 {
+			t.Errorf("Rebuilds() after Flush() = %d, want 2", got)
+		}
+
+		cache.Flush()
+
+		if got := cache.Rebuilds(); got != 2 {
+			t.Errorf("Rebuilds() after Flush() with nothing queued = %d, want 2", got)
+		}
+	})
+
+	t.Run("Publishes queued patterns after the interval", func(t *testing.T) {
+		t.Parallel()
+
+		cache := cowre.NewWithBatching(10, 100, time.Millisecond)
+
+		get(t, cache, "a")
+
+		deadline := time.Now().Add(5 * time.Second)
+
+		for cache.Rebuilds() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("queued pattern was never published")
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+
+		if got := cache.Size(); got != 1 {
+			t.Errorf("Size() = %d, want 1", got)
+		}
+	})
+
+	t.Run("Evicts the least frequently used", func(t *testing.T) {
+		t.Parallel()
+
+		cache := cowre.NewWithBatching(3, 1, 0, cowre.WithSampleRate(1))
+
+		for _, pattern := range []string{"a", "b", "c", "a", "b", "c", "a", "c"} {
+			get(t, cache, pattern)
+		}
+
+		get(t, cache, "d")
+
+		if got := cache.Size(); got != 3 {
+			t.Fatalf("Size() = %d, want 3", got)
+		}
+
+		if cache.Delete("b", recache.DefaultFlag) {
+			t.Errorf("least frequently used pattern %q was not evicted", "b")
+		}
+
+		if got := cache.Stats().Evictions; got != 1 {
+			t.Errorf("Stats().Evictions = %d, want 1", got)
+		}
+	})
+
+	t.Run("Samples hits", func(t *testing.T) {
+		t.Parallel()
+
+		const hits = 1000
+
+		cache := cowre.NewWithBatching(10, 1, 0)
+
+		for i := 0; i <= hits; i++ {
+			get(t, cache, "a")
+		}
+
+		if got := cache.Stats().Hits; got != hits {
+			t.Errorf("Stats().Hits = %d, want %d", got, hits)
+		}
+
+		// Only about one hit in DefaultSampleRate is recorded on the entry.
+		if got := cache.Entries()[0].Frequency(); got == 0 || got >= hits/2 {
+			t.Errorf("Frequency() = %d, want about %d", got, hits/cowre.DefaultSampleRate)
+		}
+	})
+
+	t.Run("Full cache waits for the interval", func(t *testing.T) {
+		t.Parallel()
+
+		cache := cowre.NewWithBatching(2, 1, time.Hour)
+
+		for i := 0; i < 5; i++ {
+			get(t, cache, fmt.Sprintf("p%d", i))
+		}
+
+		if got := cache.Size(); got != 2 {
+			t.Errorf("Size() = %d, want 2", got)
+		}
+
+		if got := cache.Stats().Evictions; got != 0 {
+			t.Errorf("Stats().Evictions = %d, want 0", got)
+		}
+
+		if !cache.Delete("p0", recache.DefaultFlag) || !cache.Delete("p1", recache.DefaultFlag) {
+			t.Errorf("patterns cached before the cache was full were not kept")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		t.Parallel()
+
+		cache := cowre.NewWithBatching(10, 2, time.Hour)
+
+		get(t, cache, "a")
+		get(t, cache, "b")
+		get(t, cache, "c")
+
+		// "a" and "b" are published, "c" is queued.
+		for _, pattern := range []string{"a", "c"} {
+			if !cache.Delete(pattern, recache.DefaultFlag) {
+				t.Errorf("Delete(%q) = false, want true", pattern)
+			}
+		}
+
+		if cache.Delete("a", recache.DefaultFlag) {
+			t.Errorf("Delete() of a deleted pattern = true, want false")
+		}
+
+		if got := cache.Size(); got != 1 {
+			t.Errorf("Size() = %d, want 1", got)
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+
+		recachetest.Run(t, func(capacity int) recache.Cache {
+			return cowre.New(capacity)
+		})
+	})
+
+	t.Run("Unbatched", func(t *testing.T) {
+		t.Parallel()
+
+		recachetest.Run(t, func(capacity int) recache.Cache {
+			return cowre.NewWithBatching(capacity, 1, 0)
+		})
+	})
+
+	t.Run("Clock", func(t *testing.T) {
+		t.Parallel()
+
+		recachetest.RunClock(t, func(capacity int, clock recache.Clock) recache.Cache {
+			return cowre.New(capacity, cowre.WithClock(clock), cowre.WithSampleRate(1))
+		})
+	})
+}
@@ -0,0 +1,123 @@
+package debughttp
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// _flags holds every flag a pattern can be compiled with.
+var _flags = []recache.Flag{
+	recache.DefaultFlag,
+	recache.FlagPOSIX,
+	recache.FlagMust,
+	recache.FlagMustPOSIX,
+}
+
+// actionResult is the JSON response to a POST request.
+type actionResult struct {
+	Action  string `json:"action"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// serveAction applies the action of an authorized POST request to a cache.
+func (h *Handler) serveAction(w http.ResponseWriter, r *http.Request, name string, cache recache.Cache) {
+	if h.authorize == nil || !h.authorize(r) || !sameOrigin(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+		return
+	}
+
+	result := actionResult{
+		Action: r.FormValue("action"),
+	}
+
+	switch result.Action {
+	case "clear":
+		cache.Clear()
+	case "capacity":
+		capacity, err := strconv.Atoi(r.FormValue("capacity"))
+		if err != nil {
+			http.Error(w, "invalid capacity", http.StatusBadRequest)
+
+			return
+		}
+
+		if err = cache.SetCapacity(capacity); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	case "delete":
+		deleter, ok := cache.(recache.Deleter)
+		if !ok {
+			http.Error(w, "cache does not support deleting entries", http.StatusNotImplemented)
+
+			return
+		}
+
+		flag, ok := parseFlag(r.FormValue("flag"))
+		if !ok {
+			http.Error(w, "invalid flag", http.StatusBadRequest)
+
+			return
+		}
+
+		result.Deleted = deleter.Delete(r.FormValue("pattern"), flag)
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+
+		return
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, result)
+
+		return
+	}
+
+	// Set the header directly rather than calling http.Redirect, which would
+	// resolve the location against the path with the prefix stripped.
+	w.Header().Set("Location", cachePath(r, name))
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// parseFlag returns the flag with the given string representation. An empty
+// string is the default flag.
+func parseFlag(s string) (recache.Flag, bool) {
+	if s == "" {
+		return recache.DefaultFlag, true
+	}
+
+	for _, flag := range _flags {
+		if flag.String() == s {
+			return flag, true
+		}
+	}
+
+	return recache.DefaultFlag, false
+}
+
+// sameOrigin reports whether the request was sent by a page from the same
+// origin, which guards the HTML forms against cross-site request forgery. It
+// checks the Origin header, or the Referer header if there is none, and
+// rejects requests with neither.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Referer()
+	}
+
+	if origin == "" {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Host == r.Host
+}
@@ -0,0 +1,259 @@
+// Package debughttp provides an [http.Handler] for inspecting and managing
+// [recache.Cache] implementations on a live process, similar to
+// [net/http/pprof].
+//
+// Unlike net/http/pprof, the handler is not registered automatically. Mount it
+// under a prefix of your choosing and strip that prefix:
+//
+//	handler := debughttp.New(debughttp.BearerToken(os.Getenv("DEBUG_TOKEN")))
+//	if err := handler.Register("routes", cache); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	http.Handle("/debug/recache/", http.StripPrefix("/debug/recache", handler))
+//
+// The index page lists every registered cache, and each cache has a page
+// listing its entries with their pattern, flag, frequency, and age. Both are
+// rendered as HTML, or as JSON if the request has a format=json query
+// parameter or accepts application/json.
+//
+// A cache page also accepts POST requests with an action form value of
+// "clear", "capacity" (with a capacity value), or "delete" (with pattern and
+// flag values). These requests must be authorized by the [Authorizer] given to
+// New, and are rejected if there is none or if they come from another origin.
+// The origin is taken from the Origin header, or the Referer header if there
+// is none, and requests with neither are rejected, so clients other than
+// browsers must set one of them:
+//
+//	curl -H "Authorization: Bearer $DEBUG_TOKEN" -H "Origin: https://example.com" \
+//		-d action=clear https://example.com/debug/recache/routes
+//
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+// [net/http/pprof]: https://godocs.io/net/http/pprof
+package debughttp
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+const (
+	// ErrDuplicateName is returned when registering a cache under a name that
+	// is already in use.
+	ErrDuplicateName xerrors.Error = "a cache with this name is already registered"
+
+	// ErrInvalidName is returned when registering a cache with an empty name
+	// or a name containing a slash.
+	ErrInvalidName xerrors.Error = "invalid cache name"
+)
+
+// Authorizer reports whether a request is allowed to modify caches.
+type Authorizer func(r *http.Request) bool
+
+// BearerToken returns an Authorizer that accepts requests with an
+// Authorization header holding the given bearer token. An empty token
+// rejects every request.
+func BearerToken(token string) Authorizer {
+	return func(r *http.Request) bool {
+		if token == "" {
+			return false
+		}
+
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return false
+		}
+
+		return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+	}
+}
+
+// BasicAuth returns an Authorizer that accepts requests with HTTP basic
+// authentication credentials matching the given ones, which browsers send
+// along with the forms on the HTML views. An empty password rejects every
+// request.
+func BasicAuth(username, password string) Authorizer {
+	return func(r *http.Request) bool {
+		if password == "" {
+			return false
+		}
+
+		givenUsername, givenPassword, ok := r.BasicAuth()
+		if !ok {
+			return false
+		}
+
+		usernameMatch := subtle.ConstantTimeCompare([]byte(givenUsername), []byte(username)) == 1
+		passwordMatch := subtle.ConstantTimeCompare([]byte(givenPassword), []byte(password)) == 1
+
+		return usernameMatch && passwordMatch
+	}
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithClock sets the clock the handler reads the time from to compute the age
+// of entries, which should be the clock the registered caches read the time
+// from. If not set, or nil, the system clock is used.
+func WithClock(clock recache.Clock) Option {
+	return func(h *Handler) {
+		if clock == nil {
+			clock = recache.SystemClock()
+		}
+
+		h.clock = clock
+	}
+}
+
+// Handler serves pages for inspecting and managing a set of named caches.
+type Handler struct {
+	// caches is a map of the registered caches' names to the caches.
+	caches map[string]recache.Cache
+
+	// clock is the clock the age of entries is computed with.
+	clock recache.Clock
+
+	// authorize reports whether a request may modify caches.
+	authorize Authorizer
+
+	// mu is a mutex that protects access to the registered caches.
+	mu sync.RWMutex
+}
+
+// Compile-time check to ensure Handler implements the http.Handler interface.
+var _ http.Handler = (*Handler)(nil)
+
+// New returns a new Handler with no registered caches and the given options.
+// POST requests are only accepted if authorize is not nil and returns true for
+// them.
+func New(authorize Authorizer, opts ...Option) *Handler {
+	h := &Handler{
+		caches:    make(map[string]recache.Cache),
+		clock:     recache.SystemClock(),
+		authorize: authorize,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Register adds a cache to the handler under the given name.
+func (h *Handler) Register(name string, cache recache.Cache) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.caches[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateName, name)
+	}
+
+	h.caches[name] = cache
+
+	return nil
+}
+
+// Unregister removes the cache with the given name from the handler.
+func (h *Handler) Unregister(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.caches, name)
+}
+
+// ServeHTTP serves the index page on the root path and the page of each
+// registered cache on the path matching its name.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+
+	if name == "" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		h.serveIndex(w, r)
+
+		return
+	}
+
+	cache, ok := h.cache(name)
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.serveCache(w, r, name, cache)
+	case http.MethodPost:
+		h.serveAction(w, r, name, cache)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// cache returns the registered cache with the given name.
+func (h *Handler) cache(name string) (recache.Cache, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	cache, ok := h.caches[name]
+
+	return cache, ok
+}
+
+// names returns the names of the registered caches, sorted.
+func (h *Handler) names() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	names := make([]string, 0, len(h.caches))
+
+	for name := range h.caches {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// cachePath returns the absolute path of the page of the cache with the given
+// name, or of the index page if name is empty. The prefix the handler is
+// mounted under is what the request URI has in front of the path the handler
+// sees, so links keep working whether or not the prefix ends with a slash.
+func cachePath(r *http.Request, name string) string {
+	var prefix string
+
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		if before, ok := strings.CutSuffix(u.Path, r.URL.Path); ok {
+			prefix = before
+		}
+	}
+
+	return strings.TrimSuffix(prefix, "/") + "/" + url.PathEscape(name)
+}
+
+// wantsJSON reports whether the request asks for the JSON view.
+func wantsJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json")
+}
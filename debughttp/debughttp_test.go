@@ -0,0 +1,359 @@
+package debughttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/debughttp"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+)
+
+const _testToken = "secret"
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		cache   = lrure.New(recache.DefaultCapacity)
+		handler = debughttp.New(debughttp.BearerToken(_testToken))
+	)
+
+	for _, pattern := range []string{`^a$`, `^a$`, `^<b>$`} {
+		if _, err := cache.Get(ctx, pattern, recache.FlagPOSIX); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	if err := handler.Register("routes", cache); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if err := handler.Register("routes", cache); !errors.Is(err, debughttp.ErrDuplicateName) {
+		t.Errorf("Register() error = %v, want %v", err, debughttp.ErrDuplicateName)
+	}
+
+	if err := handler.Register("a/b", cache); !errors.Is(err, debughttp.ErrInvalidName) {
+		t.Errorf("Register() error = %v, want %v", err, debughttp.ErrInvalidName)
+	}
+
+	serve := func(method, target string, form url.Values, token string) *httptest.ResponseRecorder {
+		var req *http.Request
+
+		if form != nil {
+			req = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest(method, target, http.NoBody)
+		}
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		req.Header.Set("Origin", "http://example.com")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Index", func(t *testing.T) {
+		t.Parallel()
+
+		rec := serve(http.MethodGet, "/", nil, "")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<a href="/routes">routes</a>`) {
+			t.Errorf("GET / = %d %q", rec.Code, rec.Body.String())
+		}
+
+		if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(rec.Body.Len()); got != want {
+			t.Errorf("GET / Content-Length = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Unknown cache", func(t *testing.T) {
+		t.Parallel()
+
+		if rec := serve(http.MethodGet, "/unknown", nil, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET /unknown = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Cache HTML", func(t *testing.T) {
+		t.Parallel()
+
+		rec := serve(http.MethodGet, "/routes", nil, "")
+		body := rec.Body.String()
+
+		if rec.Code != http.StatusOK || !strings.Contains(body, "<code>^&lt;b&gt;$</code>") {
+			t.Errorf("GET /routes = %d, want the escaped pattern in %q", rec.Code, body)
+		}
+
+		if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(len(body)); got != want {
+			t.Errorf("GET /routes Content-Length = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+
+		form := url.Values{"action": {"clear"}}
+
+		for _, token := range []string{"", "wrong"} {
+			if rec := serve(http.MethodPost, "/routes", form, token); rec.Code != http.StatusForbidden {
+				t.Errorf("POST /routes with token %q = %d, want %d", token, rec.Code, http.StatusForbidden)
+			}
+		}
+	})
+
+	t.Run("Cross-origin", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name   string
+			header string
+			value  string
+		}{
+			{
+				name:   "Origin from another host",
+				header: "Origin",
+				value:  "https://evil.example.com",
+			},
+			{
+				name:   "Referer from another host",
+				header: "Referer",
+				value:  "https://evil.example.com/page",
+			},
+			{
+				name: "Neither Origin nor Referer",
+			},
+		}
+
+		for _, tt := range tests {
+			req := httptest.NewRequest(http.MethodPost, "/routes", strings.NewReader("action=clear"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", "Bearer "+_testToken)
+
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusForbidden || cache.Size() == 0 {
+				t.Errorf("%s: POST = %d, size %d, want %d and a non-empty cache", tt.name, rec.Code, cache.Size(), http.StatusForbidden)
+			}
+		}
+	})
+
+	t.Run("Basic authentication", func(t *testing.T) {
+		t.Parallel()
+
+		authorize := debughttp.BasicAuth("admin", "hunter2")
+
+		req := httptest.NewRequest(http.MethodPost, "/routes", http.NoBody)
+		if authorize(req) {
+			t.Errorf("BasicAuth() accepted a request without credentials")
+		}
+
+		req.SetBasicAuth("admin", "hunter2")
+		if !authorize(req) {
+			t.Errorf("BasicAuth() rejected valid credentials")
+		}
+	})
+}
+
+func TestHandlerActions(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		cache   = lrure.New(recache.DefaultCapacity)
+		handler = debughttp.New(debughttp.BearerToken(_testToken))
+	)
+
+	for _, pattern := range []string{`^a$`, `^a$`, `^b$`} {
+		if _, err := cache.Get(ctx, pattern, recache.FlagPOSIX); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	if err := handler.Register("routes", cache); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/routes?format=json", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+_testToken)
+		req.Header.Set("Referer", "http://example.com/routes")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/routes", http.NoBody)
+	req.Header.Set("Accept", "application/json")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var view struct {
+		Entries []struct {
+			Pattern   string `json:"pattern"`
+			Flag      string `json:"flag"`
+			Frequency uint64 `json:"frequency"`
+		} `json:"entries"`
+		Size int `json:"size"`
+	}
+
+	if err := json.NewDecoder(rec.Body).Decode(&view); err != nil {
+		t.Fatalf("invalid JSON view: %v", err)
+	}
+
+	if view.Size != 2 || len(view.Entries) != 2 || view.Entries[0].Pattern != `^a$` || view.Entries[0].Flag != "POSIX" || view.Entries[0].Frequency != 1 {
+		t.Fatalf("GET /routes = %+v", view)
+	}
+
+	if rec = post(url.Values{"action": {"delete"}, "pattern": {`^a$`}, "flag": {"POSIX"}}); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted":true`) {
+		t.Errorf("delete = %d %q", rec.Code, rec.Body.String())
+	}
+
+	if cache.Size() != 1 {
+		t.Errorf("Size() after delete = %d, want 1", cache.Size())
+	}
+
+	if rec = post(url.Values{"action": {"capacity"}, "capacity": {"0"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("capacity 0 = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if rec = post(url.Values{"action": {"capacity"}, "capacity": {"7"}}); rec.Code != http.StatusOK || cache.Capacity() != 7 {
+		t.Errorf("capacity 7 = %d, capacity %d", rec.Code, cache.Capacity())
+	}
+
+	if rec = post(url.Values{"action": {"clear"}}); rec.Code != http.StatusOK || cache.Size() != 0 {
+		t.Errorf("clear = %d, size %d", rec.Code, cache.Size())
+	}
+
+	if rec = post(url.Values{"action": {"unknown"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown action = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandlerPrefix(t *testing.T) {
+	t.Parallel()
+
+	handler := debughttp.New(debughttp.BearerToken(_testToken))
+
+	if err := handler.Register("my routes", lrure.New(recache.DefaultCapacity)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		givePrefix string
+		giveIndex  string
+	}{
+		{
+			name:       "Prefix without a trailing slash",
+			givePrefix: "/debug/recache",
+			giveIndex:  "/debug/recache/",
+		},
+		{
+			name:       "Prefix with a trailing slash",
+			givePrefix: "/debug/recache/",
+			giveIndex:  "/debug/recache/",
+		},
+		{
+			name:       "Index without a trailing slash",
+			givePrefix: "/debug/recache",
+			giveIndex:  "/debug/recache",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				mounted = http.StripPrefix(tt.givePrefix, handler)
+				want    = "/debug/recache/my%20routes"
+			)
+
+			req := httptest.NewRequest(http.MethodGet, tt.giveIndex, http.NoBody)
+			rec := httptest.NewRecorder()
+			mounted.ServeHTTP(rec, req)
+
+			if link := `<a href="` + want + `">`; !strings.Contains(rec.Body.String(), link) {
+				t.Errorf("GET %s = %q, want a link to %s", tt.giveIndex, rec.Body.String(), want)
+			}
+
+			req = httptest.NewRequest(http.MethodPost, want, strings.NewReader("action=clear"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", "Bearer "+_testToken)
+			req.Header.Set("Origin", "http://example.com")
+
+			rec = httptest.NewRecorder()
+			mounted.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != want {
+				t.Errorf("POST %s = %d, Location %q, want %d, %q", want, rec.Code, rec.Header().Get("Location"), http.StatusSeeOther, want)
+			}
+		})
+	}
+}
+
+func TestHandlerClock(t *testing.T) {
+	t.Parallel()
+
+	var (
+		start   = time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+		clock   = recachetest.NewFakeClock(start)
+		cache   = lrure.New(recache.DefaultCapacity, recache.WithClock(clock))
+		handler = debughttp.New(nil, debughttp.WithClock(clock))
+	)
+
+	if _, err := cache.Get(context.Background(), `^a$`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if err := handler.Register("routes", cache); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	clock.Advance(90 * time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/routes?format=json", http.NoBody)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var view struct {
+		Entries []struct {
+			Age string `json:"age"`
+		} `json:"entries"`
+	}
+
+	if err := json.NewDecoder(rec.Body).Decode(&view); err != nil {
+		t.Fatalf("invalid JSON view: %v", err)
+	}
+
+	if len(view.Entries) != 1 || view.Entries[0].Age != "1h30m0s" {
+		t.Errorf("GET /routes entries = %+v, want one entry aged 1h30m0s", view.Entries)
+	}
+}
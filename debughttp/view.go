@@ -0,0 +1,178 @@
+package debughttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// _templates holds the HTML views of the handler.
+var _templates = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>recache</title></head>
+<body>
+<h1>recache</h1>
+<table>
+<tr><th>Cache</th><th>Size</th><th>Capacity</th></tr>
+{{range .}}<tr><td><a href="{{.Path}}">{{.Name}}</a></td><td>{{.Size}}</td><td>{{.Capacity}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func init() {
+	template.Must(_templates.New("cache").Parse(`<!DOCTYPE html>
+<html>
+<head><title>recache: {{.Name}}</title></head>
+<body>
+<h1>{{.Name}}</h1>
+<p>{{.Size}} of {{.Capacity}} entries.</p>
+{{with .Stats}}<p>{{.Hits}} hits, {{.Misses}} misses, {{.Evictions}} evictions, {{.CompileErrors}} compile errors.</p>
+{{end}}<form method="post"><input type="hidden" name="action" value="clear"><button>Clear</button></form>
+<form method="post"><input type="hidden" name="action" value="capacity"><input name="capacity" value="{{.Capacity}}"><button>Set capacity</button></form>
+{{if .Listed}}<table>
+<tr><th>Pattern</th><th>Flag</th><th>Frequency</th><th>Age</th><th></th></tr>
+{{range .Entries}}<tr><td><code>{{.Pattern}}</code></td><td>{{.Flag}}</td><td>{{.Frequency}}</td><td>{{.Age}}</td><td><form method="post"><input type="hidden" name="action" value="delete"><input type="hidden" name="pattern" value="{{.Pattern}}"><input type="hidden" name="flag" value="{{.Flag}}"><button>Delete</button></form></td></tr>
+{{end}}</table>
+{{else}}<p>This cache does not list its entries.</p>
+{{end}}</body>
+</html>
+`))
+}
+
+// cacheSummary describes a cache on the index page.
+type cacheSummary struct {
+	Name     string `json:"name"`
+	Path     string `json:"-"`
+	Size     int    `json:"size"`
+	Capacity int    `json:"capacity"`
+}
+
+// cacheView describes a cache and its entries.
+type cacheView struct {
+	Stats    *recache.Stats `json:"stats,omitempty"`
+	Name     string         `json:"name"`
+	Entries  []entryView    `json:"entries,omitempty"`
+	Size     int            `json:"size"`
+	Capacity int            `json:"capacity"`
+	Listed   bool           `json:"-"`
+}
+
+// entryView describes a cache entry.
+type entryView struct {
+	Created   time.Time `json:"created"`
+	Pattern   string    `json:"pattern"`
+	Flag      string    `json:"flag"`
+	Age       string    `json:"age"`
+	Frequency uint64    `json:"frequency"`
+}
+
+// serveIndex writes the list of registered caches.
+func (h *Handler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	names := h.names()
+	summaries := make([]cacheSummary, 0, len(names))
+
+	for _, name := range names {
+		if cache, ok := h.cache(name); ok {
+			summaries = append(summaries, cacheSummary{
+				Name:     name,
+				Path:     cachePath(r, name),
+				Size:     cache.Size(),
+				Capacity: cache.Capacity(),
+			})
+		}
+	}
+
+	render(w, r, "index", summaries)
+}
+
+// serveCache writes the details and entries of a cache.
+func (h *Handler) serveCache(w http.ResponseWriter, r *http.Request, name string, cache recache.Cache) {
+	view := cacheView{
+		Name:     name,
+		Size:     cache.Size(),
+		Capacity: cache.Capacity(),
+	}
+
+	if reporter, ok := cache.(recache.StatsReporter); ok {
+		stats := reporter.Stats()
+		view.Stats = &stats
+	}
+
+	if lister, ok := cache.(recache.EntryLister); ok {
+		view.Listed = true
+
+		now := h.clock.Now()
+
+		for _, entry := range lister.Entries() {
+			view.Entries = append(view.Entries, entryView{
+				Created:   entry.Created(),
+				Pattern:   entry.Pattern(),
+				Flag:      entry.Flag().String(),
+				Age:       now.Sub(entry.Created()).Round(time.Second).String(),
+				Frequency: entry.Frequency(),
+			})
+		}
+
+		sort.Slice(view.Entries, func(i, j int) bool {
+			if view.Entries[i].Frequency != view.Entries[j].Frequency {
+				return view.Entries[i].Frequency > view.Entries[j].Frequency
+			}
+
+			return view.Entries[i].Pattern < view.Entries[j].Pattern
+		})
+	}
+
+	render(w, r, "cache", view)
+}
+
+// render writes data as JSON or using the HTML template with the given name,
+// depending on the request.
+func render(w http.ResponseWriter, r *http.Request, name string, data any) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if wantsJSON(r) {
+		writeJSON(w, data)
+
+		return
+	}
+
+	// Render the whole page first, so an error can still be reported with a
+	// status code instead of being appended to a partial body.
+	var buf bytes.Buffer
+
+	if err := _templates.ExecuteTemplate(&buf, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	write(w, "text/html; charset=utf-8", &buf)
+}
+
+// writeJSON writes data as JSON.
+func writeJSON(w http.ResponseWriter, data any) {
+	var buf bytes.Buffer
+
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	write(w, "application/json; charset=utf-8", &buf)
+}
+
+// write writes a fully rendered body with the given content type.
+func write(w http.ResponseWriter, contentType string, buf *bytes.Buffer) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+
+	buf.WriteTo(w) //nolint:errcheck // the client is gone if this fails
+}
@@ -0,0 +1,217 @@
+package recache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sync/atomic"
+)
+
+// defaultCache holds the cache used by the package-level functions.
+type defaultCache struct {
+	cache Cache
+}
+
+// _default is the process-wide cache used by the package-level functions.
+var _default atomic.Pointer[defaultCache]
+
+func init() {
+	_default.Store(&defaultCache{cache: newDefault()})
+}
+
+// Default returns the process-wide cache used by the package-level functions,
+// such as MatchString and ReplaceAllString.
+//
+// Unless replaced with SetDefault, the default cache is a [Store] with the LRU
+// policy, holding up to [DefaultCapacity] regular expressions. Its capacity can
+// be changed by calling SetCapacity on the returned value, and its stats read
+// by asserting it to a [StatsReporter].
+func Default() Cache {
+	return _default.Load().cache
+}
+
+// SetDefault replaces the process-wide cache used by the package-level
+// functions. Passing nil restores the built-in LRU cache.
+func SetDefault(cache Cache) {
+	if cache == nil {
+		cache = newDefault()
+	}
+
+	_default.Store(&defaultCache{cache: cache})
+}
+
+// newDefault returns the built-in default cache.
+func newDefault() Cache {
+	return NewStore(DefaultCapacity, NewLRUPolicy())
+}
+
+// Match reports whether the byte slice b contains any match of the regular
+// expression pattern, like [regexp.Match], but compiles the pattern through
+// the default cache.
+func Match(pattern string, b []byte) (bool, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return re.Match(b), nil
+}
+
+// MatchString reports whether the string s contains any match of the regular
+// expression pattern, like [regexp.MatchString], but compiles the pattern
+// through the default cache.
+func MatchString(pattern, s string) (bool, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return re.MatchString(s), nil
+}
+
+// MatchReader reports whether the text returned by the RuneReader contains any
+// match of the regular expression pattern, like [regexp.MatchReader], but
+// compiles the pattern through the default cache.
+func MatchReader(pattern string, r io.RuneReader) (bool, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return re.MatchReader(r), nil
+}
+
+// QuoteMeta returns a string that escapes all regular expression
+// metacharacters inside the argument text. It is the same as
+// [regexp.QuoteMeta] and exists so code can switch imports without changes.
+func QuoteMeta(s string) string {
+	return regexp.QuoteMeta(s)
+}
+
+// FindString returns a string holding the text of the leftmost match in s of
+// the regular expression pattern. See [regexp.Regexp.FindString].
+func FindString(pattern, s string) (string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return re.FindString(s), nil
+}
+
+// FindStringIndex returns a two-element slice of integers defining the
+// location of the leftmost match in s of the regular expression pattern. See
+// [regexp.Regexp.FindStringIndex].
+func FindStringIndex(pattern, s string) ([]int, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.FindStringIndex(s), nil
+}
+
+// FindStringSubmatch returns a slice of strings holding the text of the
+// leftmost match of the regular expression pattern in s and the matches of
+// its subexpressions. See [regexp.Regexp.FindStringSubmatch].
+func FindStringSubmatch(pattern, s string) ([]string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.FindStringSubmatch(s), nil
+}
+
+// FindAllString returns a slice of up to n successive matches of the regular
+// expression pattern in s. See [regexp.Regexp.FindAllString].
+func FindAllString(pattern, s string, n int) ([]string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.FindAllString(s, n), nil
+}
+
+// FindAllStringIndex returns a slice of up to n successive match locations of
+// the regular expression pattern in s. See [regexp.Regexp.FindAllStringIndex].
+func FindAllStringIndex(pattern, s string, n int) ([][]int, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.FindAllStringIndex(s, n), nil
+}
+
+// FindAllStringSubmatch returns a slice of up to n successive matches of the
+// regular expression pattern in s and of its subexpressions. See
+// [regexp.Regexp.FindAllStringSubmatch].
+func FindAllStringSubmatch(pattern, s string, n int) ([][]string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.FindAllStringSubmatch(s, n), nil
+}
+
+// ReplaceAllString returns a copy of src, replacing matches of the regular
+// expression pattern with the replacement string repl, expanding $ signs. See
+// [regexp.Regexp.ReplaceAllString].
+func ReplaceAllString(pattern, src, repl string) (string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return re.ReplaceAllString(src, repl), nil
+}
+
+// ReplaceAllLiteralString returns a copy of src, replacing matches of the
+// regular expression pattern with the replacement string repl, without
+// expansion. See [regexp.Regexp.ReplaceAllLiteralString].
+func ReplaceAllLiteralString(pattern, src, repl string) (string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return re.ReplaceAllLiteralString(src, repl), nil
+}
+
+// ReplaceAllStringFunc returns a copy of src in which all matches of the
+// regular expression pattern have been replaced by the return value of repl.
+// See [regexp.Regexp.ReplaceAllStringFunc].
+func ReplaceAllStringFunc(pattern, src string, repl func(string) string) (string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return re.ReplaceAllStringFunc(src, repl), nil
+}
+
+// Split slices s into substrings separated by matches of the regular
+// expression pattern. See [regexp.Regexp.Split].
+func Split(pattern, s string, n int) ([]string, error) {
+	re, err := get(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return re.Split(s, n), nil
+}
+
+// get returns the compiled regular expression for the given pattern from the
+// default cache.
+func get(pattern string) (*regexp.Regexp, error) {
+	re, err := Default().Get(context.Background(), pattern, DefaultFlag)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return re, nil
+}
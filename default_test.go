@@ -0,0 +1,129 @@
+package recache_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+func TestPackageFunctions(t *testing.T) {
+	t.Parallel()
+
+	matched, err := recache.MatchString(`^p([a-z]+)ch$`, "peach")
+	if err != nil || !matched {
+		t.Errorf("MatchString() = %v, %v, want true, nil", matched, err)
+	}
+
+	matched, err = recache.Match(`^p([a-z]+)ch$`, []byte("punch"))
+	if err != nil || !matched {
+		t.Errorf("Match() = %v, %v, want true, nil", matched, err)
+	}
+
+	matched, err = recache.MatchReader(`^p([a-z]+)ch$`, strings.NewReader("pinch"))
+	if err != nil || !matched {
+		t.Errorf("MatchReader() = %v, %v, want true, nil", matched, err)
+	}
+
+	found, err := recache.FindAllString(`\d+`, "a1b22c333", -1)
+	if err != nil || !reflect.DeepEqual(found, []string{"1", "22", "333"}) {
+		t.Errorf("FindAllString() = %v, %v", found, err)
+	}
+
+	replaced, err := recache.ReplaceAllString(`a(x*)b`, "-ab-axxb-", "${1}W")
+	if err != nil || replaced != "-W-xxW-" {
+		t.Errorf("ReplaceAllString() = %q, %v, want %q", replaced, err, "-W-xxW-")
+	}
+
+	split, err := recache.Split(`,\s*`, "a, b,c", -1)
+	if err != nil || !reflect.DeepEqual(split, []string{"a", "b", "c"}) {
+		t.Errorf("Split() = %v, %v", split, err)
+	}
+
+	if _, err = recache.MatchString(_testPatternInvalid, "["); err == nil {
+		t.Errorf("MatchString() with an invalid pattern should return an error")
+	}
+
+	var syntaxErr *syntax.Error
+	if _, err = recache.FindString(_testPatternInvalid, "["); !errors.As(err, &syntaxErr) {
+		t.Errorf("FindString() error = %v, want a *syntax.Error", err)
+	}
+}
+
+// TestSetDefault is not parallel because it replaces the process-wide cache.
+func TestSetDefault(t *testing.T) { //nolint:paralleltest // modifies global state
+	custom := &countingCache{Cache: recache.Default()}
+
+	recache.SetDefault(custom)
+	defer recache.SetDefault(nil)
+
+	if recache.Default() != custom {
+		t.Fatalf("Default() did not return the cache passed to SetDefault()")
+	}
+
+	if _, err := recache.MatchString(`^default$`, "default"); err != nil {
+		t.Fatalf("MatchString() error = %v", err)
+	}
+
+	if custom.gets != 1 {
+		t.Errorf("MatchString() used the default cache %d times, want 1", custom.gets)
+	}
+
+	recache.SetDefault(nil)
+
+	if recache.Default() == nil || recache.Default() == custom {
+		t.Errorf("SetDefault(nil) did not restore the built-in cache")
+	}
+
+	cache := recache.Default()
+
+	if cache.Capacity() != recache.DefaultCapacity {
+		t.Errorf("Capacity() = %d, want %d", cache.Capacity(), recache.DefaultCapacity)
+	}
+
+	if err := cache.SetCapacity(1); err != nil {
+		t.Fatalf("SetCapacity() error = %v", err)
+	}
+
+	for _, pattern := range []string{`^one$`, `^two$`} {
+		if _, err := recache.MatchString(pattern, "one"); err != nil {
+			t.Fatalf("MatchString() error = %v", err)
+		}
+	}
+
+	if cache.Size() != 1 {
+		t.Errorf("Size() = %d, want 1", cache.Size())
+	}
+
+	reporter, ok := cache.(recache.StatsReporter)
+	if !ok {
+		t.Fatalf("the built-in cache does not implement StatsReporter")
+	}
+
+	if stats := reporter.Stats(); stats.Misses != 2 || stats.Evictions != 1 {
+		t.Errorf("Stats() = %d misses, %d evictions, want 2, 1", stats.Misses, stats.Evictions)
+	}
+
+	cache.Clear()
+
+	if cache.Size() != 0 {
+		t.Errorf("Size() after Clear() = %d, want 0", cache.Size())
+	}
+}
+
+// countingCache counts the number of calls to Get.
+type countingCache struct {
+	recache.Cache
+	gets int
+}
+
+func (c *countingCache) Get(ctx context.Context, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
+	c.gets++
+
+	return c.Cache.Get(ctx, pattern, flag) //nolint:wrapcheck // test helper
+}
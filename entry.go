@@ -3,7 +3,9 @@ package recache
 import (
 	"regexp"
 	"sync/atomic"
+	"time"
 
+	"git.sr.ht/~jamesponddotco/recache-go/internal/xregexp"
 	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
 )
 
@@ -11,33 +13,90 @@ import (
 const ErrInvalidEntry xerrors.Error = "invalid entry"
 
 // Entry represents an item in the cache.
+//
+// The compiled regular expression held by an entry is never handed out
+// directly. Callers always receive a shallow copy, so calling a mutating
+// method such as Longest on the returned value does not affect other callers.
 type Entry struct {
+	created   time.Time
+	clock     Clock
 	regex     *regexp.Regexp
 	pattern   string
 	key       string
 	frequency atomic.Uint64
+	accessed  atomic.Int64
+	flag      Flag
 }
 
-// NewEntry creates a new entry in the cache.
+// NewEntry creates a new entry in the cache for the given pattern compiled
+// with the default flag, using the system clock for its timestamps. Use
+// NewEntryWithFlag for patterns compiled with another flag.
 func NewEntry(key, pattern string, regex *regexp.Regexp) *Entry {
+	return NewEntryWithFlag(key, pattern, DefaultFlag, regex)
+}
+
+// NewEntryWithFlag is like NewEntry, but records the flag the pattern was
+// compiled with, which Flag returns.
+func NewEntryWithFlag(key, pattern string, flag Flag, regex *regexp.Regexp) *Entry {
+	return NewEntryWithClock(key, pattern, flag, regex, SystemClock())
+}
+
+// NewEntryWithClock is like NewEntryWithFlag, but reads the time for the entry's
+// creation and access timestamps from the given clock. A nil clock is the
+// system clock.
+func NewEntryWithClock(key, pattern string, flag Flag, regex *regexp.Regexp, clock Clock) *Entry {
 	if pattern == "" || regex == nil {
 		return nil
 	}
 
+	if clock == nil {
+		clock = SystemClock()
+	}
+
 	return &Entry{
-		regex:     regex,
+		created:   clock.Now(),
+		clock:     clock,
+		regex:     xregexp.Copy(regex),
 		pattern:   pattern,
 		key:       key,
 		frequency: atomic.Uint64{},
+		flag:      flag,
 	}
 }
 
-// Load returns the compiled regex and pattern, and increments the frequency of
-// the entry by one.
+// Load returns a copy of the compiled regex and the pattern, increments the
+// frequency of the entry by one, and records the time of the access.
 func (e *Entry) Load() (*regexp.Regexp, string, error) {
+	e.Touch()
+
+	return xregexp.Copy(e.regex), e.pattern, nil
+}
+
+// Touch records an access to the entry like Load does, without copying the
+// compiled regex. It is meant for policies that simulate caches with entries
+// that only serve as metadata.
+func (e *Entry) Touch() {
 	e.frequency.Add(1)
+	e.accessed.Store(e.clock.Now().UnixNano())
+}
 
-	return e.regex, e.pattern, nil
+// Clone returns a copy of the entry with the same metadata, whose accesses are
+// tracked separately from the original's, so loading either one does not
+// affect how a policy ranks the other.
+func (e *Entry) Clone() *Entry {
+	cp := &Entry{
+		created: e.created,
+		clock:   e.clock,
+		regex:   e.regex,
+		pattern: e.pattern,
+		key:     e.key,
+		flag:    e.flag,
+	}
+
+	cp.frequency.Store(e.frequency.Load())
+	cp.accessed.Store(e.accessed.Load())
+
+	return cp
 }
 
 // Pattern returns the entry's pattern.
@@ -45,11 +104,32 @@ func (e *Entry) Pattern() string {
 	return e.pattern
 }
 
+// Flag returns the flag the entry's pattern was compiled with.
+func (e *Entry) Flag() Flag {
+	return e.flag
+}
+
 // Key returns the entry's cache key.
 func (e *Entry) Key() string {
 	return e.key
 }
 
+// Created returns the time the entry was created.
+func (e *Entry) Created() time.Time {
+	return e.created
+}
+
+// Accessed returns the time the entry was last loaded, or the time it was
+// created if it was never loaded.
+func (e *Entry) Accessed() time.Time {
+	accessed := e.accessed.Load()
+	if accessed == 0 {
+		return e.created
+	}
+
+	return time.Unix(0, accessed).In(e.created.Location())
+}
+
 // Frequency returns the number of times the entry has been loaded.
 func (e *Entry) Frequency() uint64 {
 	return e.frequency.Load()
@@ -3,8 +3,10 @@ package recache_test
 import (
 	"regexp"
 	"testing"
+	"time"
 
 	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
 )
 
 func TestNewEntry(t *testing.T) {
@@ -51,6 +53,8 @@ func TestNewEntry(t *testing.T) {
 				t.Errorf("NewEntry() should return nil, got %v", got)
 			} else if !tt.wantNil && got == nil {
 				t.Errorf("NewEntry() should not return nil")
+			} else if got != nil && got.Flag() != recache.DefaultFlag {
+				t.Errorf("NewEntry() flag = %v, want %v", got.Flag(), recache.DefaultFlag)
 			}
 		})
 	}
@@ -61,7 +65,7 @@ func TestEntryMethods(t *testing.T) {
 
 	var (
 		regex = regexp.MustCompile(_testPattern)
-		entry = recache.NewEntry("test_key", _testPattern, regex)
+		entry = recache.NewEntryWithFlag("test_key", _testPattern, recache.FlagPOSIX, regex)
 	)
 
 	t.Run("Load", func(t *testing.T) {
@@ -72,10 +76,14 @@ func TestEntryMethods(t *testing.T) {
 			t.Errorf("Load() returned an error: %v", err)
 		}
 
-		if loadedRegex != regex {
+		if loadedRegex.String() != regex.String() {
 			t.Errorf("Load() returned incorrect regex: expected %v, got %v", regex, loadedRegex)
 		}
 
+		if loadedRegex == regex {
+			t.Errorf("Load() returned the cached regex instead of a copy")
+		}
+
 		if loadedPattern != _testPattern {
 			t.Errorf("Load() returned incorrect pattern: expected %v, got %v", _testPattern, loadedPattern)
 		}
@@ -90,6 +98,15 @@ func TestEntryMethods(t *testing.T) {
 		}
 	})
 
+	t.Run("Flag", func(t *testing.T) {
+		t.Parallel()
+
+		flag := entry.Flag()
+		if flag != recache.FlagPOSIX {
+			t.Errorf("Flag() returned incorrect flag: expected %v, got %v", recache.FlagPOSIX, flag)
+		}
+	})
+
 	t.Run("Pattern", func(t *testing.T) {
 		t.Parallel()
 
@@ -99,9 +116,22 @@ func TestEntryMethods(t *testing.T) {
 		}
 	})
 
+	t.Run("Created", func(t *testing.T) {
+		t.Parallel()
+
+		created := entry.Created()
+		if created.IsZero() || created.After(time.Now()) {
+			t.Errorf("Created() returned an invalid time: %v", created)
+		}
+	})
+
 	t.Run("Frequency", func(t *testing.T) {
 		t.Parallel()
 
+		// The other subtests load the shared entry in parallel, so count on an
+		// entry of its own.
+		entry := recache.NewEntry("test_key", _testPattern, regex)
+
 		for i := 0; i < 3; i++ {
 			_, _, err := entry.Load()
 			if err != nil {
@@ -110,8 +140,118 @@ func TestEntryMethods(t *testing.T) {
 		}
 
 		frequency := entry.Frequency()
-		if frequency != 4 { // Including the first Load() call in the "Load" subtest
-			t.Errorf("Frequency() returned incorrect value: expected %v, got %v", 4, frequency)
+		if frequency != 3 {
+			t.Errorf("Frequency() returned incorrect value: expected %v, got %v", 3, frequency)
 		}
 	})
 }
+
+func TestEntryLoadIsolation(t *testing.T) {
+	t.Parallel()
+
+	entry := recache.NewEntry("test_key", `a+?`, regexp.MustCompile(`a+?`))
+
+	first, _, err := entry.Load()
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	first.Longest()
+
+	second, _, err := entry.Load()
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	if got := second.FindString("aaa"); got != "a" {
+		t.Errorf("Longest() on a loaded regex leaked to other callers: FindString() = %q, want %q", got, "a")
+	}
+}
+
+func TestEntryClock(t *testing.T) {
+	t.Parallel()
+
+	var (
+		start = time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+		clock = recachetest.NewFakeClock(start)
+		entry = recache.NewEntryWithClock("test_key", _testPattern, recache.DefaultFlag, regexp.MustCompile(_testPattern), clock)
+	)
+
+	if got := entry.Created(); !got.Equal(start) {
+		t.Errorf("Created() = %v, want %v", got, start)
+	}
+
+	if got := entry.Accessed(); !got.Equal(start) {
+		t.Errorf("Accessed() before Load() = %v, want the creation time %v", got, start)
+	}
+
+	clock.Advance(time.Hour)
+
+	if _, _, err := entry.Load(); err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	if got, want := entry.Accessed(), start.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Accessed() after Load() = %v, want %v", got, want)
+	}
+
+	if got := entry.Created(); !got.Equal(start) {
+		t.Errorf("Created() after Load() = %v, want %v", got, start)
+	}
+}
+
+func TestEntryClone(t *testing.T) {
+	t.Parallel()
+
+	var (
+		start = time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+		clock = recachetest.NewFakeClock(start)
+		entry = recache.NewEntryWithClock("test_key", _testPattern, recache.FlagPOSIX, regexp.MustCompile(_testPattern), clock)
+	)
+
+	if _, _, err := entry.Load(); err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	cp := entry.Clone()
+
+	if cp.Key() != entry.Key() || cp.Pattern() != entry.Pattern() || cp.Flag() != entry.Flag() {
+		t.Errorf("Clone() = %q, %q, %v, want %q, %q, %v", cp.Key(), cp.Pattern(), cp.Flag(), entry.Key(), entry.Pattern(), entry.Flag())
+	}
+
+	if cp.Frequency() != 1 || !cp.Created().Equal(start) || !cp.Accessed().Equal(start) {
+		t.Errorf("Clone() metadata = %d, %v, %v, want 1, %v, %v", cp.Frequency(), cp.Created(), cp.Accessed(), start, start)
+	}
+
+	clock.Advance(time.Hour)
+
+	if _, _, err := cp.Load(); err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	if entry.Frequency() != 1 || !entry.Accessed().Equal(start) {
+		t.Errorf("Load() on a clone changed the original to %d, %v, want 1, %v", entry.Frequency(), entry.Accessed(), start)
+	}
+}
+
+// _sink keeps the regular expressions returned in benchmarks from being
+// optimized away.
+var _sink *regexp.Regexp
+
+// BenchmarkEntryLoad measures the cost of a hit on an entry, including the copy
+// of the regular expression handed out to the caller.
+func BenchmarkEntryLoad(b *testing.B) {
+	entry := recache.NewEntry("test_key", _testPattern, regexp.MustCompile(_testPattern))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		regex, _, err := entry.Load()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		_sink = regex
+	}
+}
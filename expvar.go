@@ -0,0 +1,103 @@
+package recache
+
+import (
+	"expvar"
+	"sort"
+)
+
+// _expvarHottest is the number of most frequently used patterns published by
+// PublishExpvar.
+const _expvarHottest int = 10
+
+// expvarState is the value published by PublishExpvar.
+type expvarState struct {
+	Stats    *expvarStats    `json:"stats,omitempty"`
+	Hottest  []expvarPattern `json:"hottest,omitempty"`
+	Capacity int             `json:"capacity"`
+	Size     int             `json:"size"`
+}
+
+// expvarStats holds the counters of caches that implement StatsReporter.
+type expvarStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	CompileErrors uint64 `json:"compile_errors"`
+}
+
+// expvarPattern is one of the most frequently used patterns in a cache, along
+// with the flag it was compiled with, as the same pattern can be cached once
+// per flag.
+type expvarPattern struct {
+	Pattern   string `json:"pattern"`
+	Flag      string `json:"flag"`
+	Frequency uint64 `json:"frequency"`
+}
+
+// PublishExpvar publishes the state of the given cache as an [expvar.Func]
+// under the given name, so it shows up on /debug/vars.
+//
+// The published value always includes the cache's capacity and size. Hits,
+// misses, evictions, and compile errors are included if the cache implements
+// StatsReporter, and the most frequently used patterns, with their flags, are
+// included if it implements EntryLister.
+//
+// Like [expvar.Publish], PublishExpvar panics if the name is already in use.
+func PublishExpvar(name string, cache Cache) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return newExpvarState(cache)
+	}))
+}
+
+// newExpvarState returns a snapshot of the state of the given cache.
+func newExpvarState(cache Cache) expvarState {
+	state := expvarState{
+		Capacity: cache.Capacity(),
+		Size:     cache.Size(),
+	}
+
+	if reporter, ok := cache.(StatsReporter); ok {
+		stats := reporter.Stats()
+
+		state.Stats = &expvarStats{
+			Hits:          stats.Hits,
+			Misses:        stats.Misses,
+			Evictions:     stats.Evictions,
+			CompileErrors: stats.CompileErrors,
+		}
+	}
+
+	if lister, ok := cache.(EntryLister); ok {
+		entries := lister.Entries()
+
+		hottest := make([]expvarPattern, 0, len(entries))
+
+		for _, entry := range entries {
+			hottest = append(hottest, expvarPattern{
+				Pattern:   entry.Pattern(),
+				Flag:      entry.Flag().String(),
+				Frequency: entry.Frequency(),
+			})
+		}
+
+		sort.Slice(hottest, func(i, j int) bool {
+			if hottest[i].Frequency != hottest[j].Frequency {
+				return hottest[i].Frequency > hottest[j].Frequency
+			}
+
+			if hottest[i].Pattern != hottest[j].Pattern {
+				return hottest[i].Pattern < hottest[j].Pattern
+			}
+
+			return hottest[i].Flag < hottest[j].Flag
+		})
+
+		if len(hottest) > _expvarHottest {
+			hottest = hottest[:_expvarHottest]
+		}
+
+		state.Hottest = hottest
+	}
+
+	return state
+}
@@ -0,0 +1,80 @@
+package recache_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = lrure.New(recache.DefaultCapacity)
+	)
+
+	for _, pattern := range []string{`^cold$`, `^hot$`, `^hot$`, `^hot$`} {
+		if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	// The same pattern compiled with another flag is a separate entry.
+	if _, err := cache.Get(ctx, `^cold$`, recache.FlagPOSIX); err != nil {
+		t.Fatalf("Get(%q) error = %v", `^cold$`, err)
+	}
+
+	recache.PublishExpvar("recache_test_publish_expvar", cache)
+
+	v := expvar.Get("recache_test_publish_expvar")
+	if v == nil {
+		t.Fatalf("PublishExpvar() did not publish the variable")
+	}
+
+	var got struct {
+		Stats struct {
+			Hits   uint64 `json:"hits"`
+			Misses uint64 `json:"misses"`
+		} `json:"stats"`
+		Hottest []struct {
+			Pattern   string `json:"pattern"`
+			Flag      string `json:"flag"`
+			Frequency uint64 `json:"frequency"`
+		} `json:"hottest"`
+		Capacity int `json:"capacity"`
+		Size     int `json:"size"`
+	}
+
+	if err := json.Unmarshal([]byte(v.String()), &got); err != nil {
+		t.Fatalf("published value is not valid JSON: %v", err)
+	}
+
+	if got.Capacity != recache.DefaultCapacity || got.Size != 3 {
+		t.Errorf("capacity = %d, size = %d, want %d and 3", got.Capacity, got.Size, recache.DefaultCapacity)
+	}
+
+	if got.Stats.Hits != 2 || got.Stats.Misses != 3 {
+		t.Errorf("hits = %d, misses = %d, want 2 and 3", got.Stats.Hits, got.Stats.Misses)
+	}
+
+	if len(got.Hottest) != 3 || got.Hottest[0].Pattern != `^hot$` || got.Hottest[0].Frequency != 2 {
+		t.Fatalf("hottest = %+v, want ^hot$ first with a frequency of 2", got.Hottest)
+	}
+
+	if got.Hottest[1].Flag != recache.DefaultFlag.String() || got.Hottest[2].Flag != recache.FlagPOSIX.String() {
+		t.Errorf("hottest = %+v, want ^cold$ once per flag", got.Hottest)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("PublishExpvar() with a name in use should panic")
+		}
+	}()
+
+	recache.PublishExpvar("recache_test_publish_expvar", cache)
+}
@@ -0,0 +1,157 @@
+package recache
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+// ErrFrozen is returned when changing the capacity of a frozen cache.
+const ErrFrozen xerrors.Error = "cache is frozen"
+
+// MissMode controls how a frozen cache handles patterns it does not hold.
+type MissMode int
+
+const (
+	// MissCompile compiles patterns the frozen cache does not hold on every
+	// lookup, without caching them.
+	MissCompile MissMode = iota
+
+	// MissError returns [ErrNotFound] for patterns the frozen cache
+	// does not hold.
+	MissError
+)
+
+// String returns a string representation of the miss mode.
+func (m MissMode) String() string {
+	if m == MissError {
+		return "Error"
+	}
+
+	return "Compile"
+}
+
+// FreezeOption configures a Frozen cache.
+type FreezeOption func(*Frozen)
+
+// WithMissMode sets how the frozen cache handles patterns it does not hold. If
+// not set, they are compiled without being cached.
+func WithMissMode(mode MissMode) FreezeOption {
+	return func(f *Frozen) {
+		f.missMode = mode
+	}
+}
+
+// Frozen is an immutable view of the entries a [Store] held when it was
+// frozen. Lookups take no lock, and the set of regular expressions it holds
+// never changes, apart from being emptied by Clear. Its entries are copies of
+// the store's, whose access counts start from those at the time of freezing.
+type Frozen struct {
+	// entries holds the immutable map of keys to entries.
+	entries atomic.Pointer[map[string]*Entry]
+
+	// counters keeps track of the frozen cache's activity.
+	counters Counters
+
+	// capacity is the capacity of the store when it was frozen.
+	capacity int
+
+	// missMode controls how patterns that are not held are handled.
+	missMode MissMode
+}
+
+// Compile-time check to ensure Frozen implements the Cache,
+// StatsReporter, and EntryLister interfaces.
+var (
+	_ Cache         = (*Frozen)(nil)
+	_ StatsReporter = (*Frozen)(nil)
+	_ EntryLister   = (*Frozen)(nil)
+)
+
+// Get returns a compiled regular expression from the frozen cache given a
+// pattern and an optional flag.
+//
+// If the regular expression is not in the frozen cache, it is compiled without
+// being cached, or [ErrNotFound] is returned if the cache was frozen
+// with [MissError].
+func (f *Frozen) Get(ctx context.Context, pattern string, flag Flag) (*regexp.Regexp, error) {
+	if entry, ok := (*f.entries.Load())[Key(pattern, flag)]; ok {
+		regex, _, err := entry.Load()
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		f.counters.Hit()
+		Observe(ctx, true, 0)
+
+		return regex, nil
+	}
+
+	f.counters.Miss()
+
+	if f.missMode == MissError {
+		Observe(ctx, false, 0)
+
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, pattern)
+	}
+
+	start := time.Now()
+	regex, err := Compile(pattern, flag)
+	duration := time.Since(start)
+
+	f.counters.Compiled(duration, err)
+	Observe(ctx, false, duration)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return regex, nil
+}
+
+// SetCapacity returns [ErrFrozen], as the capacity of a frozen cache cannot
+// change.
+func (*Frozen) SetCapacity(_ int) error {
+	return fmt.Errorf("%w", ErrFrozen)
+}
+
+// Capacity returns the capacity of the store when it was frozen.
+func (f *Frozen) Capacity() int {
+	return f.capacity
+}
+
+// Size returns the number of regular expressions held by the frozen cache.
+func (f *Frozen) Size() int {
+	return len(*f.entries.Load())
+}
+
+// Entries returns the entries held by the frozen cache, in no particular
+// order.
+func (f *Frozen) Entries() []*Entry {
+	var (
+		frozen  = *f.entries.Load()
+		entries = make([]*Entry, 0, len(frozen))
+	)
+
+	for _, entry := range frozen {
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// Stats returns a snapshot of the frozen cache's activity, which is tracked
+// separately from the store it was frozen from.
+func (f *Frozen) Stats() Stats {
+	return f.counters.Stats()
+}
+
+// Clear removes all regular expressions from the frozen cache, so every
+// lookup misses from then on. The store it was frozen from is not affected.
+func (f *Frozen) Clear() {
+	f.entries.Store(&map[string]*Entry{})
+}
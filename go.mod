@@ -1,5 +1,5 @@
 module git.sr.ht/~jamesponddotco/recache-go
 
-go 1.20
+go 1.21
 
 require git.sr.ht/~jamesponddotco/xstd-go v0.0.0-20230326035751-d551afedd6e5
@@ -0,0 +1,22 @@
+// Package xregexp holds helpers for regular expressions shared by the packages
+// of this module.
+package xregexp
+
+import "regexp"
+
+// Copy returns a shallow copy of the given regular expression, which is safe
+// to mutate, such as with Longest, without affecting the original.
+//
+// It is equivalent to the deprecated [regexp.Regexp.Copy]. A *regexp.Regexp
+// is safe for concurrent use, but Longest changes how it matches for every
+// caller holding it, so the caches hand out copies of the regular expressions
+// they share. The copy shares the compiled program with the original, so it
+// costs a single allocation of about 160 bytes on 64-bit platforms, and no
+// compilation, on every hit.
+//
+// [regexp.Regexp.Copy]: https://godocs.io/regexp#Regexp.Copy
+func Copy(regex *regexp.Regexp) *regexp.Regexp {
+	cp := *regex
+
+	return &cp
+}
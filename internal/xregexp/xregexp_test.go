@@ -0,0 +1,42 @@
+package xregexp_test
+
+import (
+	"regexp"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go/internal/xregexp"
+)
+
+// _sink keeps the regular expressions returned in benchmarks from being
+// optimized away.
+var _sink *regexp.Regexp
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	var (
+		regex = regexp.MustCompile(`a+?`)
+		cp    = xregexp.Copy(regex)
+	)
+
+	if cp == regex || cp.String() != regex.String() {
+		t.Fatalf("Copy() = %p %q, want a distinct copy of %p %q", cp, cp, regex, regex)
+	}
+
+	cp.Longest()
+
+	if got := regex.FindString("aaa"); got != "a" {
+		t.Errorf("Longest() on a copy changed the original: FindString() = %q, want %q", got, "a")
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	regex := regexp.MustCompile(`p([a-z]+)ch`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_sink = xregexp.Copy(regex)
+	}
+}
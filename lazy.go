@@ -0,0 +1,361 @@
+package recache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+
+	"git.sr.ht/~jamesponddotco/recache-go/internal/xregexp"
+)
+
+// _never is a regular expression that never matches anything. It stands in
+// for patterns that failed to compile, so every method of LazyRegexp behaves
+// as if there was no match.
+var _never = regexp.MustCompile(`[^\x00-\x{10FFFF}]`)
+
+// LazyRegexp is a regular expression that is compiled the first time it is
+// used, rather than when the program starts.
+//
+// It is meant to replace package-level variables initialized with
+// regexp.MustCompile, which are all compiled at init time whether they are
+// used or not. A LazyRegexp has the read-only method set of [regexp.Regexp]
+// and is safe for concurrent use.
+//
+// If the pattern fails to compile and the flag includes FlagMust, the first
+// use panics, like regexp.MustCompile would, and so does every use after that.
+//
+// Without FlagMust, an invalid pattern does not fail loudly: every method
+// behaves as if the regular expression matched nothing, so an invalid pattern
+// looks the same as an input that does not match. Call Err, for example from
+// a test or at startup, to tell the two apart, or use Regexp, which returns
+// the compile error.
+type LazyRegexp struct {
+	// cache is the optional cache used to compile the pattern.
+	cache Cache
+
+	// regex is the compiled regular expression, or _never if compilation
+	// failed.
+	regex *regexp.Regexp
+
+	// err is the error returned when compiling the pattern, if any.
+	err error
+
+	// panicked holds the value compilation panicked with when the flag
+	// includes FlagMust and the pattern is invalid.
+	panicked any
+
+	// pattern is the regular expression pattern.
+	pattern string
+
+	// flag controls how the pattern is compiled.
+	flag Flag
+
+	// once ensures the pattern is compiled exactly once.
+	once sync.Once
+}
+
+// Lazy returns a regular expression that is compiled with the given flag the
+// first time it is used.
+//
+// It is cheap enough to call at init time:
+//
+//	var _email = recache.Lazy(`^[a-z]+@[a-z]+\.[a-z]{2,}$`, recache.DefaultFlag)
+func Lazy(pattern string, flag Flag) *LazyRegexp {
+	return &LazyRegexp{
+		pattern: pattern,
+		flag:    flag,
+	}
+}
+
+// LazyCache is like Lazy, but compiles the regular expression through the
+// given cache on first use. If cache is nil, the pattern is compiled directly.
+func LazyCache(cache Cache, pattern string, flag Flag) *LazyRegexp {
+	return &LazyRegexp{
+		cache:   cache,
+		pattern: pattern,
+		flag:    flag,
+	}
+}
+
+// Err compiles the regular expression if needed and returns the compile
+// error, if any.
+func (l *LazyRegexp) Err() error {
+	l.compile()
+
+	return l.err
+}
+
+// Regexp compiles the regular expression if needed and returns a copy of it,
+// or the compile error if compilation failed.
+func (l *LazyRegexp) Regexp() (*regexp.Regexp, error) {
+	l.compile()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	return xregexp.Copy(l.regex), nil
+}
+
+// String returns the source text used to compile the regular expression,
+// without compiling it.
+func (l *LazyRegexp) String() string {
+	return l.pattern
+}
+
+// MarshalText implements encoding.TextMarshaler. The output matches that of
+// calling the String method, and it does not compile the regular expression.
+func (l *LazyRegexp) MarshalText() ([]byte, error) {
+	return []byte(l.pattern), nil
+}
+
+// compile compiles the regular expression exactly once, panicking if the
+// compilation panicked.
+func (l *LazyRegexp) compile() {
+	l.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				l.regex = _never
+				l.err = fmt.Errorf("%v", r)
+				l.panicked = r
+			}
+		}()
+
+		var (
+			regex *regexp.Regexp
+			err   error
+		)
+
+		if l.cache != nil {
+			regex, err = l.cache.Get(context.Background(), l.pattern, l.flag)
+		} else {
+			regex, err = Compile(l.pattern, l.flag)
+		}
+
+		if err != nil {
+			l.regex = _never
+			l.err = fmt.Errorf("%w", err)
+
+			return
+		}
+
+		l.regex = regex
+	})
+
+	if l.panicked != nil {
+		panic(l.panicked)
+	}
+}
+
+// regexp returns the compiled regular expression, compiling it if needed.
+func (l *LazyRegexp) regexp() *regexp.Regexp {
+	l.compile()
+
+	return l.regex
+}
+
+// Expand compiles the regular expression on first use and calls
+// [regexp.Regexp.Expand] on it.
+func (l *LazyRegexp) Expand(dst []byte, template, src []byte, match []int) []byte {
+	return l.regexp().Expand(dst, template, src, match)
+}
+
+// ExpandString compiles the regular expression on first use and calls
+// [regexp.Regexp.ExpandString] on it.
+func (l *LazyRegexp) ExpandString(dst []byte, template, src string, match []int) []byte {
+	return l.regexp().ExpandString(dst, template, src, match)
+}
+
+// Find compiles the regular expression on first use and calls
+// [regexp.Regexp.Find] on it.
+func (l *LazyRegexp) Find(b []byte) []byte {
+	return l.regexp().Find(b)
+}
+
+// FindAll compiles the regular expression on first use and calls
+// [regexp.Regexp.FindAll] on it.
+func (l *LazyRegexp) FindAll(b []byte, n int) [][]byte {
+	return l.regexp().FindAll(b, n)
+}
+
+// FindAllIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.FindAllIndex] on it.
+func (l *LazyRegexp) FindAllIndex(b []byte, n int) [][]int {
+	return l.regexp().FindAllIndex(b, n)
+}
+
+// FindAllString compiles the regular expression on first use and calls
+// [regexp.Regexp.FindAllString] on it.
+func (l *LazyRegexp) FindAllString(s string, n int) []string {
+	return l.regexp().FindAllString(s, n)
+}
+
+// FindAllStringIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.FindAllStringIndex] on it.
+func (l *LazyRegexp) FindAllStringIndex(s string, n int) [][]int {
+	return l.regexp().FindAllStringIndex(s, n)
+}
+
+// FindAllStringSubmatch compiles the regular expression on first use and calls
+// [regexp.Regexp.FindAllStringSubmatch] on it.
+func (l *LazyRegexp) FindAllStringSubmatch(s string, n int) [][]string {
+	return l.regexp().FindAllStringSubmatch(s, n)
+}
+
+// FindAllStringSubmatchIndex compiles the regular expression on first use and
+// calls [regexp.Regexp.FindAllStringSubmatchIndex] on it.
+func (l *LazyRegexp) FindAllStringSubmatchIndex(s string, n int) [][]int {
+	return l.regexp().FindAllStringSubmatchIndex(s, n)
+}
+
+// FindAllSubmatch compiles the regular expression on first use and calls
+// [regexp.Regexp.FindAllSubmatch] on it.
+func (l *LazyRegexp) FindAllSubmatch(b []byte, n int) [][][]byte {
+	return l.regexp().FindAllSubmatch(b, n)
+}
+
+// FindAllSubmatchIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.FindAllSubmatchIndex] on it.
+func (l *LazyRegexp) FindAllSubmatchIndex(b []byte, n int) [][]int {
+	return l.regexp().FindAllSubmatchIndex(b, n)
+}
+
+// FindIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.FindIndex] on it.
+func (l *LazyRegexp) FindIndex(b []byte) []int {
+	return l.regexp().FindIndex(b)
+}
+
+// FindReaderIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.FindReaderIndex] on it.
+func (l *LazyRegexp) FindReaderIndex(r io.RuneReader) []int {
+	return l.regexp().FindReaderIndex(r)
+}
+
+// FindReaderSubmatchIndex compiles the regular expression on first use and
+// calls [regexp.Regexp.FindReaderSubmatchIndex] on it.
+func (l *LazyRegexp) FindReaderSubmatchIndex(r io.RuneReader) []int {
+	return l.regexp().FindReaderSubmatchIndex(r)
+}
+
+// FindString compiles the regular expression on first use and calls
+// [regexp.Regexp.FindString] on it.
+func (l *LazyRegexp) FindString(s string) string {
+	return l.regexp().FindString(s)
+}
+
+// FindStringIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.FindStringIndex] on it.
+func (l *LazyRegexp) FindStringIndex(s string) []int {
+	return l.regexp().FindStringIndex(s)
+}
+
+// FindStringSubmatch compiles the regular expression on first use and calls
+// [regexp.Regexp.FindStringSubmatch] on it.
+func (l *LazyRegexp) FindStringSubmatch(s string) []string {
+	return l.regexp().FindStringSubmatch(s)
+}
+
+// FindStringSubmatchIndex compiles the regular expression on first use and
+// calls [regexp.Regexp.FindStringSubmatchIndex] on it.
+func (l *LazyRegexp) FindStringSubmatchIndex(s string) []int {
+	return l.regexp().FindStringSubmatchIndex(s)
+}
+
+// FindSubmatch compiles the regular expression on first use and calls
+// [regexp.Regexp.FindSubmatch] on it.
+func (l *LazyRegexp) FindSubmatch(b []byte) [][]byte {
+	return l.regexp().FindSubmatch(b)
+}
+
+// FindSubmatchIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.FindSubmatchIndex] on it.
+func (l *LazyRegexp) FindSubmatchIndex(b []byte) []int {
+	return l.regexp().FindSubmatchIndex(b)
+}
+
+// LiteralPrefix compiles the regular expression on first use and calls
+// [regexp.Regexp.LiteralPrefix] on it.
+func (l *LazyRegexp) LiteralPrefix() (prefix string, complete bool) {
+	return l.regexp().LiteralPrefix()
+}
+
+// Match compiles the regular expression on first use and calls
+// [regexp.Regexp.Match] on it.
+func (l *LazyRegexp) Match(b []byte) bool {
+	return l.regexp().Match(b)
+}
+
+// MatchReader compiles the regular expression on first use and calls
+// [regexp.Regexp.MatchReader] on it.
+func (l *LazyRegexp) MatchReader(r io.RuneReader) bool {
+	return l.regexp().MatchReader(r)
+}
+
+// MatchString compiles the regular expression on first use and calls
+// [regexp.Regexp.MatchString] on it.
+func (l *LazyRegexp) MatchString(s string) bool {
+	return l.regexp().MatchString(s)
+}
+
+// NumSubexp compiles the regular expression on first use and calls
+// [regexp.Regexp.NumSubexp] on it.
+func (l *LazyRegexp) NumSubexp() int {
+	return l.regexp().NumSubexp()
+}
+
+// ReplaceAll compiles the regular expression on first use and calls
+// [regexp.Regexp.ReplaceAll] on it.
+func (l *LazyRegexp) ReplaceAll(src, repl []byte) []byte {
+	return l.regexp().ReplaceAll(src, repl)
+}
+
+// ReplaceAllFunc compiles the regular expression on first use and calls
+// [regexp.Regexp.ReplaceAllFunc] on it.
+func (l *LazyRegexp) ReplaceAllFunc(src []byte, repl func([]byte) []byte) []byte {
+	return l.regexp().ReplaceAllFunc(src, repl)
+}
+
+// ReplaceAllLiteral compiles the regular expression on first use and calls
+// [regexp.Regexp.ReplaceAllLiteral] on it.
+func (l *LazyRegexp) ReplaceAllLiteral(src, repl []byte) []byte {
+	return l.regexp().ReplaceAllLiteral(src, repl)
+}
+
+// ReplaceAllLiteralString compiles the regular expression on first use and
+// calls [regexp.Regexp.ReplaceAllLiteralString] on it.
+func (l *LazyRegexp) ReplaceAllLiteralString(src, repl string) string {
+	return l.regexp().ReplaceAllLiteralString(src, repl)
+}
+
+// ReplaceAllString compiles the regular expression on first use and calls
+// [regexp.Regexp.ReplaceAllString] on it.
+func (l *LazyRegexp) ReplaceAllString(src, repl string) string {
+	return l.regexp().ReplaceAllString(src, repl)
+}
+
+// ReplaceAllStringFunc compiles the regular expression on first use and calls
+// [regexp.Regexp.ReplaceAllStringFunc] on it.
+func (l *LazyRegexp) ReplaceAllStringFunc(src string, repl func(string) string) string {
+	return l.regexp().ReplaceAllStringFunc(src, repl)
+}
+
+// Split compiles the regular expression on first use and calls
+// [regexp.Regexp.Split] on it.
+func (l *LazyRegexp) Split(s string, n int) []string {
+	return l.regexp().Split(s, n)
+}
+
+// SubexpIndex compiles the regular expression on first use and calls
+// [regexp.Regexp.SubexpIndex] on it.
+func (l *LazyRegexp) SubexpIndex(name string) int {
+	return l.regexp().SubexpIndex(name)
+}
+
+// SubexpNames compiles the regular expression on first use and calls
+// [regexp.Regexp.SubexpNames] on it.
+func (l *LazyRegexp) SubexpNames() []string {
+	return l.regexp().SubexpNames()
+}
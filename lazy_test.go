@@ -0,0 +1,110 @@
+package recache_test
+
+import (
+	"errors"
+	"regexp/syntax"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+)
+
+func TestLazy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Valid pattern", func(t *testing.T) {
+		t.Parallel()
+
+		re := recache.Lazy(_testPattern, recache.DefaultFlag)
+
+		if re.String() != _testPattern {
+			t.Errorf("String() = %q, want %q", re.String(), _testPattern)
+		}
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if !re.MatchString("john@example.com") {
+					t.Errorf("MatchString() = false, want true")
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		if err := re.Err(); err != nil {
+			t.Errorf("Err() = %v, want nil", err)
+		}
+
+		compiled, err := re.Regexp()
+		if err != nil || compiled.String() != _testPattern {
+			t.Errorf("Regexp() = %v, %v", compiled, err)
+		}
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		t.Parallel()
+
+		re := recache.Lazy(_testPatternInvalid, recache.DefaultFlag)
+
+		if re.MatchString("[") {
+			t.Errorf("MatchString() = true, want false")
+		}
+
+		if got := re.ReplaceAllString("[a]", "x"); got != "[a]" {
+			t.Errorf("ReplaceAllString() = %q, want %q", got, "[a]")
+		}
+
+		var syntaxErr *syntax.Error
+		if err := re.Err(); !errors.As(err, &syntaxErr) {
+			t.Errorf("Err() = %v, want a *syntax.Error", err)
+		}
+
+		if _, err := re.Regexp(); err == nil {
+			t.Errorf("Regexp() should return an error")
+		}
+	})
+
+	t.Run("Invalid pattern with Must flag", func(t *testing.T) {
+		t.Parallel()
+
+		re := recache.Lazy(_testPatternInvalid, recache.FlagMust)
+
+		for i := 0; i < 2; i++ {
+			func() {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Errorf("use %d of an invalid pattern with FlagMust should have panicked", i)
+					}
+				}()
+
+				re.MatchString("[")
+			}()
+		}
+	})
+
+	t.Run("With cache", func(t *testing.T) {
+		t.Parallel()
+
+		cache := lrure.New(recache.DefaultCapacity)
+		re := recache.LazyCache(cache, _testPattern, recache.DefaultFlag)
+
+		if cache.Size() != 0 {
+			t.Fatalf("LazyCache() compiled the pattern before first use")
+		}
+
+		if !re.MatchString("john@example.com") {
+			t.Errorf("MatchString() = false, want true")
+		}
+
+		if cache.Size() != 1 {
+			t.Errorf("Size() = %d, want 1", cache.Size())
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package recache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// logCompile logs the outcome of compiling a pattern, if it failed or was
+// slow.
+func (s *Store) logCompile(ctx context.Context, key, pattern string, flag Flag, duration time.Duration, err error) {
+	if s.logger == nil {
+		return
+	}
+
+	attrs := append(s.patternAttrs(key, pattern, flag), slog.Duration("duration", duration))
+
+	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "regex compile failed", append(attrs, slog.Any("error", err))...)
+
+		return
+	}
+
+	if s.slowCompile > 0 && duration > s.slowCompile {
+		s.logger.LogAttrs(ctx, slog.LevelInfo, "slow regex compile", append(attrs, slog.Duration("threshold", s.slowCompile))...)
+	}
+}
+
+// logEvictions logs each of the given evicted entries.
+func (s *Store) logEvictions(ctx context.Context, evicted []*Entry) {
+	if s.logger == nil {
+		return
+	}
+
+	for _, entry := range evicted {
+		attrs := append(s.patternAttrs(entry.Key(), entry.Pattern(), entry.Flag()), slog.Uint64("frequency", entry.Frequency()))
+
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "regex evicted", attrs...)
+	}
+}
+
+// logCapacity logs a change of the store's capacity.
+func (s *Store) logCapacity(previous, capacity, evicted int) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.LogAttrs(context.Background(), slog.LevelDebug, "cache capacity changed",
+		slog.Int("previous", previous),
+		slog.Int("capacity", capacity),
+		slog.Int("evicted", evicted),
+	)
+}
+
+// logClear logs a call to Clear.
+func (s *Store) logClear(size int) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.LogAttrs(context.Background(), slog.LevelInfo, "cache cleared", slog.Int("size", size))
+}
+
+// patternAttrs returns the attributes describing a pattern, taking the
+// pattern logging mode into account.
+func (s *Store) patternAttrs(key, pattern string, flag Flag) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 6)
+
+	if s.patternLogging == PatternPlain {
+		attrs = append(attrs, slog.String("pattern", pattern))
+	}
+
+	if s.patternLogging != PatternRedacted {
+		attrs = append(attrs, slog.String("key", key))
+	}
+
+	return append(attrs, slog.String("flag", flag.String()))
+}
@@ -0,0 +1,132 @@
+package recache_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+func TestStoreLogging(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		mode        recache.PatternLogging
+		wantPattern bool
+		wantKey     bool
+	}{
+		{
+			name:        "Plain patterns",
+			mode:        recache.PatternPlain,
+			wantPattern: true,
+			wantKey:     true,
+		},
+		{
+			name:    "Hashed patterns",
+			mode:    recache.PatternHashed,
+			wantKey: true,
+		},
+		{
+			name: "Redacted patterns",
+			mode: recache.PatternRedacted,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				ctx    = context.Background()
+				buf    bytes.Buffer
+				logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+				store  = recache.NewStore(1, &fifo{},
+					recache.WithLogger(logger),
+					recache.WithSlowCompile(-1),
+					recache.WithPatternLogging(tt.mode),
+				)
+			)
+
+			for _, pattern := range []string{`^a`, `^b`, `[`} {
+				store.Get(ctx, pattern, recache.DefaultFlag) //nolint:errcheck // errors are logged
+			}
+
+			if err := store.SetCapacity(2); err != nil {
+				t.Fatalf("SetCapacity() error = %v", err)
+			}
+
+			store.Clear()
+
+			var records []map[string]any
+
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("invalid log record %q: %v", line, err)
+				}
+
+				records = append(records, record)
+			}
+
+			want := []struct{ level, msg string }{
+				{"DEBUG", "regex evicted"},
+				{"WARN", "regex compile failed"},
+				{"DEBUG", "cache capacity changed"},
+				{"INFO", "cache cleared"},
+			}
+
+			if len(records) != len(want) {
+				t.Fatalf("got %d log records, want %d:\n%s", len(records), len(want), buf.String())
+			}
+
+			for i, w := range want {
+				if records[i]["level"] != w.level || records[i]["msg"] != w.msg {
+					t.Errorf("record %d = %v %q, want %v %q", i, records[i]["level"], records[i]["msg"], w.level, w.msg)
+				}
+			}
+
+			evicted := records[0]
+
+			if _, ok := evicted["pattern"]; ok != tt.wantPattern {
+				t.Errorf("pattern attribute present = %v, want %v", ok, tt.wantPattern)
+			}
+
+			if _, ok := evicted["key"]; ok != tt.wantKey {
+				t.Errorf("key attribute present = %v, want %v", ok, tt.wantKey)
+			}
+
+			if evicted["flag"] != recache.DefaultFlag.String() {
+				t.Errorf("flag attribute = %v, want %v", evicted["flag"], recache.DefaultFlag.String())
+			}
+
+			if _, ok := records[1]["duration"]; !ok {
+				t.Errorf("compile failure record has no duration attribute")
+			}
+		})
+	}
+}
+
+func TestStoreSlowCompileLogging(t *testing.T) {
+	t.Parallel()
+
+	var (
+		buf    bytes.Buffer
+		logger = slog.New(slog.NewTextHandler(&buf, nil))
+		store  = recache.NewStore(1, &fifo{}, recache.WithLogger(logger), recache.WithSlowCompile(1))
+	)
+
+	if _, err := store.Get(context.Background(), `^slow$`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "slow regex compile") {
+		t.Errorf("expected a slow compile record, got %q", buf.String())
+	}
+}
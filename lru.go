@@ -0,0 +1,68 @@
+package recache
+
+import (
+	"container/list"
+)
+
+// LRUPolicy is the least recently used cache replacement policy, which the
+// default cache and the lrure package use.
+type LRUPolicy struct {
+	// elements is a map of the cache's keys to the list elements that hold
+	// their entries.
+	elements map[string]*list.Element
+
+	// list is a doubly-linked list that holds the cache's entries in order of
+	// most recently used to least recently used.
+	list *list.List
+}
+
+// Compile-time check to ensure LRUPolicy implements the Policy interface.
+var _ Policy = (*LRUPolicy)(nil)
+
+// NewLRUPolicy returns a new LRU cache replacement policy.
+func NewLRUPolicy() *LRUPolicy {
+	return &LRUPolicy{
+		elements: make(map[string]*list.Element),
+		list:     list.New().Init(),
+	}
+}
+
+// Access marks the given entry as the most recently used.
+func (p *LRUPolicy) Access(entry *Entry) {
+	if elem, ok := p.elements[entry.Key()]; ok {
+		p.list.MoveToFront(elem)
+	}
+}
+
+// Insert adds the given entry as the most recently used.
+func (p *LRUPolicy) Insert(entry *Entry) {
+	p.elements[entry.Key()] = p.list.PushFront(entry)
+}
+
+// Victim returns the key of the least recently used entry.
+func (p *LRUPolicy) Victim() (string, bool) {
+	elem := p.list.Back()
+	if elem == nil {
+		return "", false
+	}
+
+	entry, ok := elem.Value.(*Entry)
+	if !ok {
+		return "", false
+	}
+
+	return entry.Key(), true
+}
+
+// Remove stops tracking the entry with the given key.
+func (p *LRUPolicy) Remove(key string) {
+	if elem, ok := p.elements[key]; ok {
+		p.list.Remove(elem)
+
+		delete(p.elements, key)
+	}
+}
+
+// Resize is a no-op, as the LRU policy does not depend on the capacity of the
+// cache.
+func (*LRUPolicy) Resize(_ int) {}
@@ -2,10 +2,12 @@ package lrure_test
 
 import (
 	"context"
+	"regexp"
 	"testing"
 
 	"git.sr.ht/~jamesponddotco/recache-go"
 	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
 )
 
 // TestNew tests the New function from the lrure package.
@@ -187,3 +189,100 @@ func TestSetCapacity(t *testing.T) {
 		})
 	}
 }
+
+func TestCacheGetIsolation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = lrure.New(recache.DefaultCapacity)
+	)
+
+	// The first Get compiles the pattern, the second one hits the cache.
+	for i := 0; i < 2; i++ {
+		re, err := cache.Get(ctx, `a+?`, recache.DefaultFlag)
+		if err != nil {
+			t.Fatalf("Cache.Get() error = %v, wantErr = false", err)
+		}
+
+		re.Longest()
+	}
+
+	re, err := cache.Get(ctx, `a+?`, recache.DefaultFlag)
+	if err != nil {
+		t.Fatalf("Cache.Get() error = %v, wantErr = false", err)
+	}
+
+	if got := re.FindString("aaa"); got != "a" {
+		t.Errorf("Longest() on a cached regex leaked to other callers: FindString() = %q, want %q", got, "a")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p = lrure.NewPolicy()
+		a = recache.NewEntry("a", "a", regexp.MustCompile("a"))
+		b = recache.NewEntry("b", "b", regexp.MustCompile("b"))
+	)
+
+	if _, ok := p.Victim(); ok {
+		t.Errorf("Victim() on an empty policy should return false")
+	}
+
+	p.Insert(a)
+	p.Insert(b)
+	p.Access(a)
+
+	if key, _ := p.Victim(); key != "b" {
+		t.Errorf("Victim() = %q, want %q", key, "b")
+	}
+
+	p.Remove("b")
+
+	if key, _ := p.Victim(); key != "a" {
+		t.Errorf("Victim() after Remove() = %q, want %q", key, "a")
+	}
+}
+
+func TestCachePin(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = lrure.New(2)
+	)
+
+	if err := cache.Pin(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	// Without the pin, the pattern would be the least recently used entry
+	// after each of these.
+	for _, pattern := range []string{`^a`, `^b`, `^c`} {
+		if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Cache.Get() error = %v, wantErr = false", err)
+		}
+	}
+
+	if _, err := cache.Get(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Cache.Get() error = %v, wantErr = false", err)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Errorf("pinned pattern was evicted: Stats().Hits = %d, want 1", stats.Hits)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	recachetest.Run(t, func(capacity int) recache.Cache {
+		return lrure.New(capacity)
+	})
+
+	recachetest.RunClock(t, func(capacity int, clock recache.Clock) recache.Cache {
+		return lrure.New(capacity, recache.WithClock(clock))
+	})
+}
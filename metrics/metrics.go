@@ -0,0 +1,250 @@
+// Package metrics exports the state of one or more [recache.Cache]
+// implementations in the [Prometheus text exposition format] or the
+// [OpenMetrics] format, without depending on any third-party package.
+//
+// Size and capacity are exported for every cache. Hits, misses, evictions,
+// compile errors, and the compile latency histogram are exported for caches
+// that implement [recache.StatsReporter], such as the ones in the lrure and
+// mockingjayre packages.
+//
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+// [recache.StatsReporter]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#StatsReporter
+// [Prometheus text exposition format]: https://prometheus.io/docs/instrumenting/exposition_formats/
+// [OpenMetrics]: https://openmetrics.io/
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+const (
+	// ErrDuplicateName is returned when registering a cache under a name that
+	// is already in use.
+	ErrDuplicateName xerrors.Error = "a cache with this name is already registered"
+
+	// ErrInvalidName is returned when registering a cache with an empty name.
+	ErrInvalidName xerrors.Error = "invalid cache name"
+)
+
+const (
+	// ContentTypeText is the content type of the Prometheus text exposition
+	// format.
+	ContentTypeText string = "text/plain; version=0.0.4; charset=utf-8"
+
+	// ContentTypeOpenMetrics is the content type of the OpenMetrics format.
+	ContentTypeOpenMetrics string = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Format is the exposition format used when writing metrics.
+type Format int
+
+const (
+	// FormatText is the Prometheus text exposition format.
+	FormatText Format = iota
+
+	// FormatOpenMetrics is the OpenMetrics text format.
+	FormatOpenMetrics
+)
+
+// String returns a string representation of the format.
+func (f Format) String() string {
+	if f == FormatOpenMetrics {
+		return "OpenMetrics"
+	}
+
+	return "Text"
+}
+
+// Exporter renders the metrics of a set of named caches. It implements
+// [http.Handler], so it can be mounted on a metrics endpoint directly.
+type Exporter struct {
+	// caches is a map of the registered caches' names to the caches.
+	caches map[string]recache.Cache
+
+	// mu is a mutex that protects access to the registered caches.
+	mu sync.RWMutex
+}
+
+// Compile-time check to ensure Exporter implements the http.Handler interface.
+var _ http.Handler = (*Exporter)(nil)
+
+// New returns a new Exporter with no registered caches.
+func New() *Exporter {
+	return &Exporter{
+		caches: make(map[string]recache.Cache),
+	}
+}
+
+// Register adds a cache to the exporter under the given name, which is used
+// as the value of the cache label.
+func (e *Exporter) Register(name string, cache recache.Cache) error {
+	if name == "" {
+		return fmt.Errorf("%w", ErrInvalidName)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.caches[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateName, name)
+	}
+
+	e.caches[name] = cache
+
+	return nil
+}
+
+// Unregister removes the cache with the given name from the exporter.
+func (e *Exporter) Unregister(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.caches, name)
+}
+
+// ServeHTTP writes the metrics of all registered caches, using OpenMetrics if
+// the client asks for it and the Prometheus text format otherwise.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format := FormatText
+	if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+		format = FormatOpenMetrics
+	}
+
+	// Render the whole response first, so an error can still be reported
+	// with a status code instead of being appended to a partial body.
+	var buf bytes.Buffer
+
+	if err := e.Write(&buf, format); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if format == FormatOpenMetrics {
+		w.Header().Set("Content-Type", ContentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", ContentTypeText)
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+
+	buf.WriteTo(w) //nolint:errcheck // the client is gone if this fails
+}
+
+// Write writes the metrics of all registered caches to w in the given
+// format.
+func (e *Exporter) Write(w io.Writer, format Format) error {
+	snapshots := e.snapshot()
+
+	buf := bufio.NewWriter(w)
+	m := &writer{w: buf}
+
+	m.family("recache_size", "gauge", "Number of regular expressions currently stored in the cache.")
+
+	for _, s := range snapshots {
+		m.sample("recache_size", s.name, "", strconv.Itoa(s.size))
+	}
+
+	m.family("recache_capacity", "gauge", "Maximum number of regular expressions the cache can store.")
+
+	for _, s := range snapshots {
+		m.sample("recache_capacity", s.name, "", strconv.Itoa(s.capacity))
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(recache.Stats) uint64
+	}{
+		{"recache_hits", "Number of regular expressions found in the cache.", func(s recache.Stats) uint64 { return s.Hits }},
+		{"recache_misses", "Number of regular expressions not found in the cache.", func(s recache.Stats) uint64 { return s.Misses }},
+		{"recache_evictions", "Number of entries evicted from the cache.", func(s recache.Stats) uint64 { return s.Evictions }},
+		{"recache_compile_errors", "Number of patterns that failed to compile.", func(s recache.Stats) uint64 { return s.CompileErrors }},
+	}
+
+	for _, c := range counters {
+		// OpenMetrics names counter families without the _total suffix their
+		// samples have, while the Prometheus text format uses the same name.
+		if format == FormatOpenMetrics {
+			m.family(c.name, "counter", c.help)
+		} else {
+			m.family(c.name+"_total", "counter", c.help)
+		}
+
+		for _, s := range snapshots {
+			if s.stats != nil {
+				m.sample(c.name+"_total", s.name, "", strconv.FormatUint(c.value(*s.stats), 10))
+			}
+		}
+	}
+
+	m.family("recache_compile_duration_seconds", "histogram", "Time spent compiling regular expressions.")
+
+	for _, s := range snapshots {
+		if s.stats != nil {
+			m.histogram("recache_compile_duration_seconds", s.name, s.stats.CompileLatency)
+		}
+	}
+
+	if format == FormatOpenMetrics {
+		m.printf("# EOF\n")
+	}
+
+	if m.err != nil {
+		return m.err
+	}
+
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// snapshot holds the state of a cache at a point in time.
+type snapshot struct {
+	stats    *recache.Stats
+	name     string
+	size     int
+	capacity int
+}
+
+// snapshot returns the state of all registered caches, sorted by name.
+func (e *Exporter) snapshot() []snapshot {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	snapshots := make([]snapshot, 0, len(e.caches))
+
+	for name, cache := range e.caches {
+		s := snapshot{
+			name:     name,
+			size:     cache.Size(),
+			capacity: cache.Capacity(),
+		}
+
+		if reporter, ok := cache.(recache.StatsReporter); ok {
+			stats := reporter.Stats()
+			s.stats = &stats
+		}
+
+		snapshots = append(snapshots, s)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].name < snapshots[j].name
+	})
+
+	return snapshots
+}
@@ -0,0 +1,129 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/metrics"
+)
+
+// plainCache is a cache that does not report stats.
+type plainCache struct {
+	recache.Cache
+}
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx      = context.Background()
+		cache    = lrure.New(1)
+		exporter = metrics.New()
+	)
+
+	for _, pattern := range []string{`^a`, `^a`, `^b`, `[`} {
+		cache.Get(ctx, pattern, recache.DefaultFlag) //nolint:errcheck // errors are counted by the cache
+	}
+
+	if err := exporter.Register("routes", cache); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if err := exporter.Register("plain\"", plainCache{Cache: lrure.New(5)}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if err := exporter.Register("routes", cache); !errors.Is(err, metrics.ErrDuplicateName) {
+		t.Errorf("Register() error = %v, want %v", err, metrics.ErrDuplicateName)
+	}
+
+	if err := exporter.Register("", cache); !errors.Is(err, metrics.ErrInvalidName) {
+		t.Errorf("Register() error = %v, want %v", err, metrics.ErrInvalidName)
+	}
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		want        []string
+		wantMissing []string
+	}{
+		{
+			name:        "Prometheus text",
+			contentType: metrics.ContentTypeText,
+			want: []string{
+				"# TYPE recache_hits_total counter\n",
+				`recache_hits_total{cache="routes"} 1` + "\n",
+				`recache_misses_total{cache="routes"} 3` + "\n",
+				`recache_evictions_total{cache="routes"} 1` + "\n",
+				`recache_compile_errors_total{cache="routes"} 1` + "\n",
+				`recache_compile_duration_seconds_bucket{cache="routes",le="+Inf"} 3` + "\n",
+				`recache_compile_duration_seconds_count{cache="routes"} 3` + "\n",
+				`recache_size{cache="routes"} 1` + "\n",
+				`recache_capacity{cache="plain\""} 5` + "\n",
+			},
+			wantMissing: []string{
+				`recache_hits_total{cache="plain\""}`,
+				"# EOF",
+			},
+		},
+		{
+			name:        "OpenMetrics",
+			accept:      "application/openmetrics-text; version=1.0.0",
+			contentType: metrics.ContentTypeOpenMetrics,
+			want: []string{
+				"# TYPE recache_hits counter\n",
+				`recache_hits_total{cache="routes"} 1` + "\n",
+				"# EOF\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				req = httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody)
+				rec = httptest.NewRecorder()
+			)
+
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			exporter.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+
+			body := rec.Body.String()
+
+			if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(len(body)) {
+				t.Errorf("Content-Length = %q, want %d for the whole rendered body", got, len(body))
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %q:\n%s", want, body)
+				}
+			}
+
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(body, missing) {
+					t.Errorf("body should not contain %q:\n%s", missing, body)
+				}
+			}
+		})
+	}
+}
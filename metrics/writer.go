@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// _labelEscaper escapes label values as required by both exposition formats.
+var _labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writer writes metric families, remembering the first error it encounters.
+type writer struct {
+	w   io.Writer
+	err error
+}
+
+// printf writes a formatted string unless a previous write failed.
+func (m *writer) printf(format string, args ...any) {
+	if m.err != nil {
+		return
+	}
+
+	if _, err := fmt.Fprintf(m.w, format, args...); err != nil {
+		m.err = fmt.Errorf("%w", err)
+	}
+}
+
+// family writes the metadata of a metric family.
+func (m *writer) family(name, typ, help string) {
+	m.printf("# HELP %s %s\n", name, help)
+	m.printf("# TYPE %s %s\n", name, typ)
+}
+
+// sample writes a single sample for the given cache. The extra argument holds
+// additional, already formatted labels.
+func (m *writer) sample(name, cache, extra, value string) {
+	labels := `cache="` + _labelEscaper.Replace(cache) + `"`
+	if extra != "" {
+		labels += "," + extra
+	}
+
+	m.printf("%s{%s} %s\n", name, labels, value)
+}
+
+// histogram writes the samples of a histogram for the given cache, converting
+// durations to seconds.
+func (m *writer) histogram(name, cache string, h recache.Histogram) {
+	var cumulative uint64
+
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+
+		le := `le="` + strconv.FormatFloat(bound.Seconds(), 'g', -1, 64) + `"`
+		m.sample(name+"_bucket", cache, le, strconv.FormatUint(cumulative, 10))
+	}
+
+	m.sample(name+"_bucket", cache, `le="+Inf"`, strconv.FormatUint(h.Count, 10))
+	m.sample(name+"_sum", cache, "", strconv.FormatFloat(h.Sum.Seconds(), 'g', -1, 64))
+	m.sample(name+"_count", cache, "", strconv.FormatUint(h.Count, 10))
+}
@@ -8,125 +8,80 @@
 package mockingjayre
 
 import (
-	"context"
-	"fmt"
-	"regexp"
-	"sync"
-
 	"git.sr.ht/~jamesponddotco/recache-go"
 )
 
 // Cache is a thread-safe regex cache using the Mockingjay policy.
 type Cache struct {
-	cache    map[string]*recache.Entry
-	capacity int
-	mu       sync.RWMutex
+	*recache.Store
 }
 
-// New returns a new Mockingjay cache with the given capacity.
+// Compile-time check to ensure Cache implements the recache.Cache interface.
+var _ recache.Cache = (*Cache)(nil)
+
+// New returns a new Mockingjay cache with the given capacity and options, such
+// as [recache.WithLogger].
 //
 // If capacity is less than 1, [recache.DefaultCapacity] is used instead.
 //
 // [recache.DefaultCapacity]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#DefaultCapacity
-func New(capacity int) *Cache {
-	if capacity < 1 {
-		capacity = recache.DefaultCapacity
-	}
-
+func New(capacity int, opts ...recache.StoreOption) *Cache {
 	return &Cache{
-		cache:    make(map[string]*recache.Entry, capacity),
-		capacity: capacity,
-	}
-}
-
-// Get returns a compiled regular expression from the cache given a pattern and
-// an optional flag. If the regular expression is not in the cache, it is
-// compiled and added to it.
-func (c *Cache) Get(_ context.Context, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
-	key := recache.Key(pattern, flag)
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if entry, ok := c.cache[key]; ok {
-		regex, _, err := entry.Load()
-		if err != nil {
-			return nil, fmt.Errorf("%w", err)
-		}
-
-		return regex, nil
-	}
-
-	regex, err := recache.Compile(pattern, flag)
-	if err != nil {
-		return nil, fmt.Errorf("%w", err)
-	}
-
-	if len(c.cache) >= c.capacity {
-		c.evict()
+		Store: recache.NewStore(capacity, NewPolicy(), opts...),
 	}
-
-	newEntry := recache.NewEntry(key, pattern, regex)
-
-	c.cache[key] = newEntry
-
-	return regex, nil
 }
 
-// SetCapacity sets the maximum number of regular expressions that can be
-// stored in the cache.
-func (c *Cache) SetCapacity(capacity int) error {
-	if capacity < 1 {
-		return fmt.Errorf("%w", recache.ErrInvalidCapacity)
-	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.capacity = capacity
-
-	return nil
+// Policy is the Mockingjay cache replacement policy, which evicts the least
+// frequently used entry.
+type Policy struct {
+	entries map[string]*recache.Entry
 }
 
-// Capacity returns the maximum number of regular expressions that can be
-// stored in the cache.
-func (c *Cache) Capacity() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// Compile-time check to ensure Policy implements the recache.Policy and
+// recache.ConcurrentAccessor interfaces.
+var _ recache.ConcurrentAccessor = (*Policy)(nil)
 
-	return c.capacity
+// NewPolicy returns a new Mockingjay cache replacement policy.
+func NewPolicy() *Policy {
+	return &Policy{
+		entries: make(map[string]*recache.Entry),
+	}
 }
 
-// Size returns the number of regular expressions currently stored in the
-// cache.
-func (c *Cache) Size() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return len(c.cache)
-}
+// Access is a no-op, as the entry keeps track of its own frequency.
+func (*Policy) Access(_ *recache.Entry) {}
 
-// Clear removes all regular expressions from the cache.
-func (c *Cache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// ConcurrentAccess marks Access as safe for concurrent use, so the store
+// serves hits under a read lock.
+func (*Policy) ConcurrentAccess() {}
 
-	c.cache = make(map[string]*recache.Entry, c.capacity)
+// Insert starts tracking the given entry.
+func (p *Policy) Insert(entry *recache.Entry) {
+	p.entries[entry.Key()] = entry
 }
 
-// evict removes the least frequently used entry from the cache.
-func (c *Cache) evict() {
+// Victim returns the key of the least frequently used entry.
+func (p *Policy) Victim() (string, bool) {
 	var (
 		leastFrequentlyUsedKey   string
 		leastFrequentlyUsedEntry *recache.Entry
 	)
 
-	for key, entry := range c.cache {
+	for key, entry := range p.entries {
 		if leastFrequentlyUsedEntry == nil || entry.Frequency() < leastFrequentlyUsedEntry.Frequency() {
 			leastFrequentlyUsedKey = key
 			leastFrequentlyUsedEntry = entry
 		}
 	}
 
-	delete(c.cache, leastFrequentlyUsedKey)
+	return leastFrequentlyUsedKey, leastFrequentlyUsedEntry != nil
 }
+
+// Remove stops tracking the entry with the given key.
+func (p *Policy) Remove(key string) {
+	delete(p.entries, key)
+}
+
+// Resize is a no-op, as the Mockingjay policy does not depend on the capacity
+// of the cache.
+func (*Policy) Resize(_ int) {}
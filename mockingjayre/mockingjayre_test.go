@@ -0,0 +1,88 @@
+package mockingjayre_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/mockingjayre"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p = mockingjayre.NewPolicy()
+		a = recache.NewEntry("a", "a", regexp.MustCompile("a"))
+		b = recache.NewEntry("b", "b", regexp.MustCompile("b"))
+	)
+
+	if _, ok := p.Victim(); ok {
+		t.Errorf("Victim() on an empty policy should return false")
+	}
+
+	p.Insert(a)
+	p.Insert(b)
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := a.Load(); err != nil {
+			t.Fatalf("Load() returned an error: %v", err)
+		}
+	}
+
+	if _, _, err := b.Load(); err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	if key, _ := p.Victim(); key != "b" {
+		t.Errorf("Victim() = %q, want %q", key, "b")
+	}
+
+	p.Remove("b")
+
+	if key, _ := p.Victim(); key != "a" {
+		t.Errorf("Victim() after Remove() = %q, want %q", key, "a")
+	}
+}
+
+func TestCachePin(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = mockingjayre.New(2)
+	)
+
+	if err := cache.Pin(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	// Make the other patterns more popular than the pinned one.
+	for _, pattern := range []string{`^a`, `^a`, `^b`, `^b`, `^c`, `^c`} {
+		if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	if _, err := cache.Get(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 4 {
+		t.Errorf("Stats().Hits = %d, want 4 with the pinned pattern still cached", stats.Hits)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	recachetest.Run(t, func(capacity int) recache.Cache {
+		return mockingjayre.New(capacity)
+	})
+
+	recachetest.RunClock(t, func(capacity int, clock recache.Clock) recache.Cache {
+		return mockingjayre.New(capacity, recache.WithClock(clock))
+	})
+}
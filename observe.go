@@ -0,0 +1,50 @@
+package recache
+
+import (
+	"context"
+	"time"
+)
+
+// Observation describes the outcome of a single call to a cache's Get method.
+// Decorators that need to know whether a lookup hit the cache, such as trace
+// recorders, attach one to the context with WithObservation, and caches fill
+// it in by calling Observe.
+type Observation struct {
+	// Compile is the time spent compiling the regular expression, or zero if
+	// it was found in the cache.
+	Compile time.Duration
+
+	// Hit reports whether the regular expression was found in the cache.
+	Hit bool
+
+	// Observed reports whether the cache filled in the observation at all.
+	// Caches that do not call Observe leave it false.
+	Observed bool
+}
+
+// observationKey is the type of the context key holding an Observation.
+type observationKey struct{}
+
+// WithObservation returns a copy of the context carrying the given
+// observation, which caches fill in when passed the context to Get.
+func WithObservation(ctx context.Context, obs *Observation) context.Context {
+	return context.WithValue(ctx, observationKey{}, obs)
+}
+
+// Observe records the outcome of a lookup in the observation carried by the
+// context, if any. Only the first call for a given observation has an effect,
+// so a cache that serves a hit itself and delegates misses to another cache
+// reports the outcome of whichever one handled the lookup.
+//
+// This function is not used by callers of the Cache interface, but is exported
+// for use by packages implementing it.
+func Observe(ctx context.Context, hit bool, compile time.Duration) {
+	obs, ok := ctx.Value(observationKey{}).(*Observation)
+	if !ok || obs == nil || obs.Observed {
+		return
+	}
+
+	obs.Compile = compile
+	obs.Hit = hit
+	obs.Observed = true
+}
@@ -0,0 +1,59 @@
+package recache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+func TestObserve(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Without observation", func(t *testing.T) {
+		t.Parallel()
+
+		// Must not panic.
+		recache.Observe(context.Background(), true, 0)
+	})
+
+	t.Run("First call wins", func(t *testing.T) {
+		t.Parallel()
+
+		var obs recache.Observation
+
+		ctx := recache.WithObservation(context.Background(), &obs)
+
+		recache.Observe(ctx, false, time.Millisecond)
+		recache.Observe(ctx, true, 0)
+
+		if !obs.Observed || obs.Hit || obs.Compile != time.Millisecond {
+			t.Errorf("Observation = %+v, want an observed miss that took 1ms", obs)
+		}
+	})
+
+	t.Run("Default cache", func(t *testing.T) {
+		t.Parallel()
+
+		const pattern = `^observe-[0-9]+$`
+
+		var miss, hit recache.Observation
+
+		if _, err := recache.Default().Get(recache.WithObservation(context.Background(), &miss), pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		if _, err := recache.Default().Get(recache.WithObservation(context.Background(), &hit), pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		if !miss.Observed || miss.Hit {
+			t.Errorf("first Get() observation = %+v, want a miss", miss)
+		}
+
+		if !hit.Observed || !hit.Hit || hit.Compile != 0 {
+			t.Errorf("second Get() observation = %+v, want a hit", hit)
+		}
+	})
+}
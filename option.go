@@ -0,0 +1,84 @@
+package recache
+
+import (
+	"log/slog"
+	"time"
+)
+
+// DefaultSlowCompile is the default duration after which compiling a pattern
+// is logged as slow.
+const DefaultSlowCompile = time.Millisecond
+
+// PatternLogging controls how patterns are included in log records.
+type PatternLogging int
+
+const (
+	// PatternPlain logs patterns as they are.
+	PatternPlain PatternLogging = iota
+
+	// PatternHashed logs the cache key of the pattern instead of the pattern
+	// itself, which still allows correlating records.
+	PatternHashed
+
+	// PatternRedacted omits both patterns and their cache keys from log
+	// records.
+	PatternRedacted
+)
+
+// String returns a string representation of the pattern logging mode.
+func (p PatternLogging) String() string {
+	switch p {
+	case PatternHashed:
+		return "Hashed"
+	case PatternRedacted:
+		return "Redacted"
+	default:
+		return "Plain"
+	}
+}
+
+// StoreOption configures a Store.
+type StoreOption func(*Store)
+
+// WithLogger attaches a structured logger to the store. Compile failures are
+// logged at the warn level, slow compiles and calls to Clear at the info level,
+// and evictions and capacity changes at the debug level.
+//
+// Records are emitted outside the store's lock. By default, nothing is logged.
+func WithLogger(logger *slog.Logger) StoreOption {
+	return func(s *Store) {
+		s.logger = logger
+	}
+}
+
+// WithSlowCompile sets the duration after which compiling a pattern is logged
+// as slow. A threshold of zero or less disables slow compile records.
+//
+// If not set, [DefaultSlowCompile] is used.
+func WithSlowCompile(threshold time.Duration) StoreOption {
+	return func(s *Store) {
+		s.slowCompile = threshold
+	}
+}
+
+// WithPatternLogging sets how patterns are included in log records, which is
+// useful when patterns may contain sensitive data. If not set, patterns are
+// logged as they are.
+func WithPatternLogging(mode PatternLogging) StoreOption {
+	return func(s *Store) {
+		s.patternLogging = mode
+	}
+}
+
+// WithClock sets the clock the store reads the time from for the timestamps of
+// its entries, which lets tests control time. Compile durations are always
+// measured with the system clock. If not set, or nil, the system clock is used.
+func WithClock(clock Clock) StoreOption {
+	return func(s *Store) {
+		if clock == nil {
+			clock = SystemClock()
+		}
+
+		s.clock = clock
+	}
+}
@@ -0,0 +1,39 @@
+package recache
+
+// Policy decides which entry should be evicted from a [Store] once it grows
+// past its capacity.
+//
+// Policies do not need to be safe for concurrent use, as the Store serializes
+// all calls to them.
+type Policy interface {
+	// Access records that the given entry was loaded from the cache.
+	Access(entry *Entry)
+
+	// Insert records that the given entry was added to the cache.
+	Insert(entry *Entry)
+
+	// Victim returns the key of the entry that should be evicted next, or
+	// false if the policy is not tracking any entries.
+	Victim() (string, bool)
+
+	// Remove stops tracking the entry with the given key.
+	Remove(key string)
+
+	// Resize informs the policy that the capacity of the cache changed.
+	Resize(capacity int)
+}
+
+// ConcurrentAccessor is implemented by policies whose Access method is safe to
+// call concurrently with itself, for example because it does nothing and the
+// policy relies on the frequency counter of [Entry] instead.
+//
+// The Store serves hits for such policies under a read lock, so concurrent
+// hits do not contend with each other. Every other method is still called
+// under the exclusive lock.
+type ConcurrentAccessor interface {
+	Policy
+
+	// ConcurrentAccess does nothing. It only marks the policy's Access
+	// method as safe for concurrent use.
+	ConcurrentAccess()
+}
@@ -0,0 +1,453 @@
+package recache
+
+import (
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// _maxLiterals is the largest number of alternative literals kept for a
+	// single pattern. Patterns needing more are always treated as candidates.
+	_maxLiterals int = 32
+
+	// _prefilterCacheSize is the number of prefilters kept by _prefilters.
+	_prefilterCacheSize int = 64
+)
+
+// _prefilters caches the prefilters of recently built sets by set key, so
+// building the same Set again does not rebuild its automaton.
+var _prefilters = newPrefilterCache(_prefilterCacheSize)
+
+// prefilter finds the patterns of a set that may match an input by searching
+// the input for the literals every match of those patterns must contain, with
+// an Aho-Corasick automaton. Patterns are identified by their index in the
+// set's patterns sorted by cache key.
+//
+// Literals and inputs are compared with ASCII letters folded to lower case,
+// which may report patterns that do not match, but never misses one that does.
+type prefilter struct {
+	// automaton searches for every literal at once.
+	automaton *ahoCorasick
+
+	// patterns maps every literal to the patterns that require it.
+	patterns [][]int
+
+	// filtered reports, for every pattern, whether it has required literals.
+	// Patterns that do not are candidates for every input.
+	filtered []bool
+}
+
+// newPrefilter extracts the required literals of the given patterns, parsed
+// with the given flag, and builds the automaton searching for them. It returns
+// nil if none of the patterns has required literals.
+func newPrefilter(patterns []string, flag Flag) *prefilter {
+	parseFlags := syntax.Perl
+	if flag == FlagPOSIX || flag == FlagMustPOSIX {
+		parseFlags = syntax.POSIX
+	}
+
+	var (
+		ids = make(map[string]int)
+		p   = &prefilter{
+			filtered: make([]bool, len(patterns)),
+		}
+		literals []string
+	)
+
+	for i, pattern := range patterns {
+		re, err := syntax.Parse(pattern, parseFlags)
+		if err != nil {
+			continue
+		}
+
+		required := requiredLiterals(re.Simplify())
+		if required == nil {
+			continue
+		}
+
+		p.filtered[i] = true
+
+		for _, literal := range required {
+			id, ok := ids[literal]
+			if !ok {
+				id = len(literals)
+				ids[literal] = id
+
+				literals = append(literals, literal)
+				p.patterns = append(p.patterns, nil)
+			}
+
+			p.patterns[id] = append(p.patterns[id], i)
+		}
+	}
+
+	if len(literals) == 0 {
+		return nil
+	}
+
+	p.automaton = newAhoCorasick(literals)
+
+	return p
+}
+
+// candidates reports, for every pattern, whether its required literals appear
+// in the input. It is always true for patterns without required literals.
+func candidates[T string | []byte](p *prefilter, input T) []bool {
+	found := make([]bool, len(p.filtered))
+
+	for i, filtered := range p.filtered {
+		found[i] = !filtered
+	}
+
+	search(p.automaton, input, func(literal int) {
+		for _, i := range p.patterns[literal] {
+			found[i] = true
+		}
+	})
+
+	return found
+}
+
+// requiredLiterals returns literals such that every match of re contains at
+// least one of them, folded with foldASCII, or nil if there are none worth
+// searching for.
+func requiredLiterals(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		literal := literalString(re)
+		if literal == "" {
+			return nil
+		}
+
+		return []string{literal}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min < 1 {
+			return nil
+		}
+
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpConcat:
+		var best []string
+
+		for _, sub := range re.Sub {
+			if literals := requiredLiterals(sub); better(literals, best) {
+				best = literals
+			}
+		}
+
+		return best
+	case syntax.OpAlternate:
+		var union []string
+
+		for _, sub := range re.Sub {
+			literals := requiredLiterals(sub)
+			if literals == nil {
+				return nil
+			}
+
+			union = append(union, literals...)
+		}
+
+		if len(union) > _maxLiterals {
+			return nil
+		}
+
+		return union
+	default:
+		return nil
+	}
+}
+
+// better reports whether the literals in a are more selective than those in b:
+// their shortest literal is longer, or as long but there are fewer of them.
+func better(a, b []string) bool {
+	if a == nil {
+		return false
+	}
+
+	if b == nil {
+		return true
+	}
+
+	if shortest(a) != shortest(b) {
+		return shortest(a) > shortest(b)
+	}
+
+	return len(a) < len(b)
+}
+
+// shortest returns the length of the shortest of the given literals.
+func shortest(literals []string) int {
+	n := len(literals[0])
+
+	for _, literal := range literals[1:] {
+		n = min(n, len(literal))
+	}
+
+	return n
+}
+
+// literalString returns the literal matched by an OpLiteral node, folded with
+// foldASCII. Case-insensitive runes whose other cases are not all ASCII, such
+// as k, which matches the Kelvin sign, cannot be folded that way, and neither
+// can U+FFFD, which matches any invalid UTF-8 in the input, so only the longest
+// run of runes between them is returned.
+func literalString(re *syntax.Regexp) string {
+	var (
+		foldCase = re.Flags&syntax.FoldCase != 0
+		longest  string
+		run      strings.Builder
+	)
+
+	for _, r := range re.Rune {
+		if r == utf8.RuneError || (foldCase && !asciiFoldable(r)) {
+			if run.Len() > len(longest) {
+				longest = run.String()
+			}
+
+			run.Reset()
+
+			continue
+		}
+
+		run.WriteRune(r)
+	}
+
+	if run.Len() > len(longest) {
+		longest = run.String()
+	}
+
+	return foldASCII(longest)
+}
+
+// asciiFoldable reports whether every case of r is ASCII, or r has no other
+// case, so that folding ASCII letters is enough to match it case-insensitively.
+func asciiFoldable(r rune) bool {
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f >= utf8.RuneSelf || r >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// foldASCII returns s with ASCII letters in lower case.
+func foldASCII(s string) string {
+	b := []byte(s)
+
+	for i, c := range b {
+		b[i] = lowerASCII(c)
+	}
+
+	return string(b)
+}
+
+// lowerASCII returns c in lower case if it is an ASCII letter, and c
+// otherwise.
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+
+	return c
+}
+
+// ahoCorasick is an Aho-Corasick automaton searching for several literals at
+// once, in a single pass over the input.
+type ahoCorasick struct {
+	// nodes holds the nodes of the trie of literals, starting with the root.
+	nodes []acNode
+}
+
+// acNode is a node of the trie of an ahoCorasick automaton.
+type acNode struct {
+	// next maps bytes to the child reached by them.
+	next map[byte]int32
+
+	// literals holds the literals ending at this node.
+	literals []int
+
+	// fail is the node for the longest proper suffix of this node's path that
+	// is also in the trie.
+	fail int32
+
+	// output is the closest node, following fail links from this node
+	// included, where a literal ends, or -1 if there is none.
+	output int32
+}
+
+// newAhoCorasick returns an automaton searching for the given literals, which
+// are reported by their index.
+func newAhoCorasick(literals []string) *ahoCorasick {
+	ac := &ahoCorasick{
+		nodes: []acNode{{next: make(map[byte]int32)}},
+	}
+
+	for i, literal := range literals {
+		var node int32
+
+		for j := 0; j < len(literal); j++ {
+			child, ok := ac.nodes[node].next[literal[j]]
+			if !ok {
+				child = int32(len(ac.nodes)) //nolint:gosec // tries never hold that many nodes
+
+				ac.nodes = append(ac.nodes, acNode{next: make(map[byte]int32)})
+				ac.nodes[node].next[literal[j]] = child
+			}
+
+			node = child
+		}
+
+		ac.nodes[node].literals = append(ac.nodes[node].literals, i)
+	}
+
+	ac.link()
+
+	return ac
+}
+
+// link sets the fail and output links of every node, breadth first so the
+// links of shorter paths are set before they are needed.
+func (ac *ahoCorasick) link() {
+	ac.nodes[0].output = -1
+
+	queue := make([]int32, 0, len(ac.nodes))
+
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		n := &ac.nodes[node]
+
+		n.output = ac.nodes[n.fail].output
+		if len(n.literals) > 0 {
+			n.output = node
+		}
+
+		for c, child := range n.next {
+			fail := n.fail
+
+			for {
+				if next, ok := ac.nodes[fail].next[c]; ok && next != child {
+					ac.nodes[child].fail = next
+
+					break
+				}
+
+				if fail == 0 {
+					break
+				}
+
+				fail = ac.nodes[fail].fail
+			}
+
+			queue = append(queue, child)
+		}
+	}
+}
+
+// search calls found with the index of every literal in the input, folded with
+// lowerASCII, possibly more than once.
+func search[T string | []byte](ac *ahoCorasick, input T, found func(literal int)) {
+	var node int32
+
+	for i := 0; i < len(input); i++ {
+		c := lowerASCII(input[i])
+
+		for {
+			if next, ok := ac.nodes[node].next[c]; ok {
+				node = next
+
+				break
+			}
+
+			if node == 0 {
+				break
+			}
+
+			node = ac.nodes[node].fail
+		}
+
+		for out := ac.nodes[node].output; out >= 0; out = ac.nodes[ac.nodes[out].fail].output {
+			for _, literal := range ac.nodes[out].literals {
+				found(literal)
+			}
+		}
+	}
+}
+
+// prefilterCache is a small thread-safe cache of prefilters by set key, which
+// evicts the oldest prefilter when full.
+type prefilterCache struct {
+	// prefilters maps set keys to their prefilters, which may be nil.
+	prefilters map[string]*prefilter
+
+	// keys holds the cached keys from oldest to newest.
+	keys []string
+
+	// capacity is the maximum number of prefilters in the cache.
+	capacity int
+
+	// mu protects access to the cache.
+	mu sync.Mutex
+}
+
+// newPrefilterCache returns an empty cache holding up to capacity prefilters.
+func newPrefilterCache(capacity int) *prefilterCache {
+	return &prefilterCache{
+		prefilters: make(map[string]*prefilter, capacity),
+		capacity:   capacity,
+	}
+}
+
+// get returns the prefilter of the set with the given key and sorted
+// patterns, building it if it is not cached.
+func (c *prefilterCache) get(key string, patterns []string, flag Flag) *prefilter {
+	c.mu.Lock()
+	p, ok := c.prefilters[key]
+	c.mu.Unlock()
+
+	if ok {
+		return p
+	}
+
+	// Build outside the lock, as sets of thousands of patterns take a while.
+	p = newPrefilter(patterns, flag)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok = c.prefilters[key]; !ok {
+		if len(c.keys) >= c.capacity {
+			delete(c.prefilters, c.keys[0])
+			c.keys = c.keys[1:]
+		}
+
+		c.prefilters[key] = p
+		c.keys = append(c.keys, key)
+	}
+
+	return p
+}
+
+// setKey returns the key identifying a set of patterns given the cache keys
+// of its distinct patterns in sorted order.
+func setKey(keys []string, flag Flag) string {
+	if !sort.StringsAreSorted(keys) {
+		keys = append([]string(nil), keys...)
+		sort.Strings(keys)
+	}
+
+	return Key(strings.Join(keys, _keySeparator), flag)
+}
@@ -21,8 +21,8 @@ const (
 const (
 	// ErrInvalidCapacity is returned when the provided capacity is less than 1.
 	//
-	// This error is not used by the package itself, but is exported for use by
-	// packages implementing the Cache interface.
+	// Store returns it, and other implementations of the Cache interface
+	// should too.
 	ErrInvalidCapacity xerrors.Error = "invalid capacity"
 
 	// ErrUnexpectedType is returned when the cache encounters an unexpected
@@ -35,23 +35,33 @@ const (
 	// ErrNotFound is returned when a regular expression is not found in the
 	// cache.
 	//
-	// This error is not used by the package itself, but is exported for use by
-	// packages implementing the Cache interface.
+	// Frozen caches return it for misses when frozen with MissError, and other
+	// implementations of the Cache interface may return it too.
 	ErrNotFound xerrors.Error = "not found in the cache"
+
+	// ErrPinLimit is returned when pinning a regular expression, or shrinking
+	// the cache, would leave more pinned regular expressions than the cache's
+	// capacity.
+	//
+	// Store returns it, and other implementations of the Pinner interface
+	// should too.
+	ErrPinLimit xerrors.Error = "pinned regular expressions would exceed the capacity"
 )
 
 // DefaultCapacity is the default maximum number of regular expressions that
 // can be stored in the cache.
 //
-// This constant is not used by the package itself, but is exported for use by
-// packages implementing the Cache interface.
+// It is the capacity of the default cache, and the one Store and other
+// implementations of the Cache interface fall back to when given an invalid
+// capacity.
 const DefaultCapacity int = 25
 
 // Compile compiles the provided regular expression pattern taking the provided
 // control flag into account.
 //
-// This function is not used by the package itself, but is exported for use by
-// packages implementing the Cache interface.
+// Every cache in this module compiles patterns with it, and other
+// implementations of the Cache interface should too, so flags behave the same
+// everywhere.
 func Compile(pattern string, flag Flag) (*regexp.Regexp, error) {
 	switch flag {
 	case FlagPOSIX:
@@ -80,8 +90,8 @@ func Compile(pattern string, flag Flag) (*regexp.Regexp, error) {
 //
 // The generated key is of the form "pattern:PATTERN:flag:FLAG".
 //
-// This function is not used by the package itself, but is exported for use by
-// packages implementing the Cache interface.
+// Store keys its entries with it, and other implementations of the Cache
+// interface can use it to key theirs.
 func Key(pattern string, flag Flag) string {
 	return xfnv.String(_keyPattern + pattern + _keySeparator + _KeyFlag + flag.String())
 }
@@ -0,0 +1,135 @@
+package recachegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// _warmFunc is the name of the generated function that compiles every
+// pattern.
+const _warmFunc string = "Warm"
+
+// _template is the template of the generated file.
+const _template = `// Code generated by recache-gen{{ with .Source }} from {{ . }}{{ end }}. DO NOT EDIT.
+
+package {{ .Package }}
+
+import "git.sr.ht/~jamesponddotco/recache-go"
+
+var (
+{{- range .Patterns }}
+	{{ variable . }} = recache.Lazy({{ quote .Pattern }}, recache.{{ flag .Flag }})
+{{- end }}
+)
+{{ range .Patterns }}
+// {{ .Accessor }} returns the regular expression compiled from the {{ .Name }} pattern
+{{- if multiline .Pattern }}.{{ else }}:
+//
+//	{{ .Pattern }}
+{{- end }}
+func {{ .Accessor }}() *recache.LazyRegexp {
+	return {{ variable . }}
+}
+{{ end }}
+// Warm compiles every regular expression up front, so the first use of each
+// one does not pay for compiling it. The patterns were validated when this file
+// was generated, so it only returns an error if the recache package disagrees.
+func Warm() error {
+	for _, regex := range []*recache.LazyRegexp{
+{{- range .Patterns }}
+		{{ variable . }},
+{{- end }}
+	} {
+		if err := regex.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+`
+
+// _flagNames maps every flag to the name of its constant in the recache
+// package.
+var _flagNames = map[recache.Flag]string{
+	recache.DefaultFlag:   "DefaultFlag",
+	recache.FlagPOSIX:     "FlagPOSIX",
+	recache.FlagMust:      "FlagMust",
+	recache.FlagMustPOSIX: "FlagMustPOSIX",
+}
+
+// Config configures the generated code.
+type Config struct {
+	// Package is the name of the generated package.
+	Package string
+
+	// Source is the name of the patterns file, mentioned in the header of the
+	// generated file. It is omitted if empty.
+	Source string
+
+	// Patterns are the patterns to generate accessors for, as returned by
+	// Parse.
+	Patterns []Pattern
+}
+
+// Generate writes a gofmt-ed Go file defining an accessor for every pattern
+// in the configuration, backed by [recache.Lazy], and a Warm function that
+// compiles them all.
+//
+// [recache.Lazy]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Lazy
+func Generate(w io.Writer, cfg Config) error {
+	if !token.IsIdentifier(cfg.Package) {
+		return fmt.Errorf("%w: package %q", ErrInvalidName, cfg.Package)
+	}
+
+	tmpl, err := template.New("recache-gen").Funcs(template.FuncMap{
+		"flag":      func(flag recache.Flag) string { return _flagNames[flag] },
+		"multiline": func(s string) bool { return strings.ContainsAny(s, "\r\n") },
+		"quote":     quote,
+		"variable":  variable,
+	}).Parse(_template)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err = tmpl.Execute(&buf, cfg); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if _, err = w.Write(src); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// quote returns a Go string literal for the given pattern, using a raw string
+// literal when possible, as regular expressions usually are.
+func quote(pattern string) string {
+	if strconv.CanBackquote(pattern) {
+		return "`" + pattern + "`"
+	}
+
+	return strconv.Quote(pattern)
+}
+
+// variable returns the name of the unexported variable holding the lazily
+// compiled pattern.
+func variable(p Pattern) string {
+	return "_" + strings.ToLower(p.Name[:1]) + p.Name[1:]
+}
@@ -0,0 +1,235 @@
+// Package recachegen generates Go code that exposes a validated set of regular
+// expressions as typed accessors backed by [recache.Lazy].
+//
+// Patterns are read from a file with one pattern per line, in the form
+//
+//	name [flag] = pattern
+//
+// where name becomes the name of the accessor, flag is the string
+// representation of a [recache.Flag] and defaults to the default flag, and
+// pattern is the rest of the line. Patterns may be wrapped in backquotes, or
+// in double quotes using Go's escape sequences. Empty lines and lines starting
+// with # are ignored:
+//
+//	# Matches email addresses.
+//	email = ^[a-z]+@[a-z]+\.[a-z]{2,}$
+//	date POSIX = `[0-9]{4}-[0-9]{2}-[0-9]{2}`
+//
+// Every pattern is compiled while parsing, so typos fail code generation, and
+// with it the build, instead of surfacing at run time.
+//
+// [recache.Lazy]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Lazy
+// [recache.Flag]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Flag
+package recachegen
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+const (
+	// ErrSyntax is returned when a line is not of the form name [flag] =
+	// pattern.
+	ErrSyntax xerrors.Error = "syntax error"
+
+	// ErrInvalidName is returned when a name is not a valid Go identifier or
+	// clashes with generated code.
+	ErrInvalidName xerrors.Error = "invalid name"
+
+	// ErrDuplicateName is returned when two patterns would have accessors with
+	// the same name.
+	ErrDuplicateName xerrors.Error = "duplicate name"
+
+	// ErrInvalidFlag is returned when a flag is not the string representation
+	// of a recache.Flag.
+	ErrInvalidFlag xerrors.Error = "invalid flag"
+
+	// ErrInvalidPattern is returned when a pattern does not compile.
+	ErrInvalidPattern xerrors.Error = "invalid pattern"
+)
+
+// _commentPrefix starts a line that is ignored.
+const _commentPrefix string = "#"
+
+// _reservedNames are the accessor names used by the generated code itself.
+var _reservedNames = map[string]bool{
+	_warmFunc: true,
+}
+
+// _flags holds every flag a pattern can be compiled with, by their string
+// representation.
+var _flags = map[string]recache.Flag{
+	recache.DefaultFlag.String():   recache.DefaultFlag,
+	recache.FlagPOSIX.String():     recache.FlagPOSIX,
+	recache.FlagMust.String():      recache.FlagMust,
+	recache.FlagMustPOSIX.String(): recache.FlagMustPOSIX,
+}
+
+// Pattern is a regular expression read from a patterns file.
+type Pattern struct {
+	// Name is the name of the pattern, as written in the file.
+	Name string
+
+	// Pattern is the regular expression.
+	Pattern string
+
+	// Line is the line of the file the pattern was read from, starting at 1.
+	Line int
+
+	// Flag is the flag the pattern is compiled with.
+	Flag recache.Flag
+}
+
+// Accessor returns the name of the generated function returning the compiled
+// pattern, which is the name of the pattern with its first letter in upper
+// case.
+func (p Pattern) Accessor() string {
+	return strings.ToUpper(p.Name[:1]) + p.Name[1:]
+}
+
+// Parse reads and validates the patterns of a patterns file. It reports every
+// invalid line at once, joined with [errors.Join], rather than stopping at the
+// first one.
+func Parse(r io.Reader) ([]Pattern, error) {
+	var (
+		scanner   = bufio.NewScanner(r)
+		accessors = make(map[string]int)
+		patterns  []Pattern
+		errs      []error
+		line      int
+	)
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, _commentPrefix) {
+			continue
+		}
+
+		p, err := parseLine(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+
+			continue
+		}
+
+		p.Line = line
+
+		if previous, ok := accessors[p.Accessor()]; ok {
+			errs = append(errs, fmt.Errorf("line %d: %w: %s is already defined on line %d", line, ErrDuplicateName, p.Accessor(), previous))
+
+			continue
+		}
+
+		accessors[p.Accessor()] = line
+		patterns = append(patterns, p)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return patterns, nil
+}
+
+// parseLine parses and validates a line that is neither empty nor a comment.
+func parseLine(text string) (Pattern, error) {
+	head, pattern, ok := strings.Cut(text, "=")
+	if !ok {
+		return Pattern{}, fmt.Errorf("%w: missing =", ErrSyntax)
+	}
+
+	fields := strings.Fields(head)
+	if len(fields) == 0 || len(fields) > 2 {
+		return Pattern{}, fmt.Errorf("%w: want name [flag] = pattern", ErrSyntax)
+	}
+
+	p := Pattern{
+		Name: fields[0],
+	}
+
+	if !validName(p.Name) || _reservedNames[p.Accessor()] {
+		return Pattern{}, fmt.Errorf("%w: %q", ErrInvalidName, p.Name)
+	}
+
+	if len(fields) == 2 {
+		if p.Flag, ok = _flags[fields[1]]; !ok {
+			return Pattern{}, fmt.Errorf("%w: %q", ErrInvalidFlag, fields[1])
+		}
+	}
+
+	pattern, err := unquote(strings.TrimSpace(pattern))
+	if err != nil {
+		return Pattern{}, err
+	}
+
+	if pattern == "" {
+		return Pattern{}, fmt.Errorf("%w: missing pattern", ErrSyntax)
+	}
+
+	p.Pattern = pattern
+
+	// Compile with the non-panicking variant of the flag, so invalid patterns
+	// are reported as errors whatever their flag.
+	flag := p.Flag
+
+	switch flag {
+	case recache.FlagMust:
+		flag = recache.DefaultFlag
+	case recache.FlagMustPOSIX:
+		flag = recache.FlagPOSIX
+	}
+
+	if _, err = recache.Compile(p.Pattern, flag); err != nil {
+		return Pattern{}, fmt.Errorf("%w: %s: %w", ErrInvalidPattern, p.Name, err)
+	}
+
+	return p, nil
+}
+
+// unquote removes the backquotes or double quotes around a pattern, if any.
+func unquote(pattern string) (string, error) {
+	if len(pattern) < 2 || pattern[0] != pattern[len(pattern)-1] || (pattern[0] != '`' && pattern[0] != '"') {
+		return pattern, nil
+	}
+
+	unquoted, err := strconv.Unquote(pattern)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid quoted pattern: %w", ErrSyntax, err)
+	}
+
+	return unquoted, nil
+}
+
+// validName reports whether name is an ASCII Go identifier starting with a
+// letter, so its first letter can be made upper case to export it.
+func validName(name string) bool {
+	if name == "" || !isLetter(name[0]) {
+		return false
+	}
+
+	for i := 1; i < len(name); i++ {
+		if c := name[i]; !isLetter(c) && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,139 @@
+package recachegen_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/recachegen"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		give         string
+		wantPatterns []recachegen.Pattern
+		wantErrs     []error
+	}{
+		{
+			name: "Valid",
+			give: "# comment\n\nemail = ^[a-z]+$\ndate POSIX = `[0-9]+`\n  quoted Must = \"a\\tb\"  \n",
+			wantPatterns: []recachegen.Pattern{
+				{Name: "email", Pattern: `^[a-z]+$`, Line: 3, Flag: recache.DefaultFlag},
+				{Name: "date", Pattern: `[0-9]+`, Line: 4, Flag: recache.FlagPOSIX},
+				{Name: "quoted", Pattern: "a\tb", Line: 5, Flag: recache.FlagMust},
+			},
+		},
+		{
+			name:     "Invalid pattern",
+			give:     "bad = a(\n",
+			wantErrs: []error{recachegen.ErrInvalidPattern},
+		},
+		{
+			name:     "Invalid POSIX pattern",
+			give:     `digits MustPOSIX = \d+`,
+			wantErrs: []error{recachegen.ErrInvalidPattern},
+		},
+		{
+			name:     "Invalid flag",
+			give:     "a Foo = a",
+			wantErrs: []error{recachegen.ErrInvalidFlag},
+		},
+		{
+			name:     "Invalid names",
+			give:     "1a = a\nwarm = a\nsnake-case = a",
+			wantErrs: []error{recachegen.ErrInvalidName},
+		},
+		{
+			name:     "Duplicate accessor",
+			give:     "email = a\nEmail = b",
+			wantErrs: []error{recachegen.ErrDuplicateName},
+		},
+		{
+			name:     "Syntax errors",
+			give:     "email\nemail =\n= a\na b c = a\nq = \"\\q\"",
+			wantErrs: []error{recachegen.ErrSyntax},
+		},
+		{
+			name:     "Every error is reported",
+			give:     "bad = a(\nok = a\nnoeq",
+			wantErrs: []error{recachegen.ErrInvalidPattern, recachegen.ErrSyntax},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := recachegen.Parse(strings.NewReader(tt.give))
+
+			for _, want := range tt.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("Parse() error = %v, want %v", err, want)
+				}
+			}
+
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if len(got) != len(tt.wantPatterns) {
+				t.Fatalf("Parse() returned %d patterns, want %d", len(got), len(tt.wantPatterns))
+			}
+
+			for i, want := range tt.wantPatterns {
+				if got[i] != want {
+					t.Errorf("Parse()[%d] = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open(filepath.Join("testdata", "patterns.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	patterns, err := recachegen.Parse(f)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("testdata", "patterns.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+
+	err = recachegen.Generate(&got, recachegen.Config{
+		Package:  "patterns",
+		Source:   "patterns.txt",
+		Patterns: patterns,
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if got.String() != string(want) {
+		t.Errorf("Generate() output does not match testdata/patterns.golden:\n%s", got.String())
+	}
+
+	err = recachegen.Generate(&got, recachegen.Config{Package: "not a package"})
+	if !errors.Is(err, recachegen.ErrInvalidName) {
+		t.Errorf("Generate() with an invalid package name error = %v, want %v", err, recachegen.ErrInvalidName)
+	}
+}
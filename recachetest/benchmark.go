@@ -0,0 +1,57 @@
+package recachetest
+
+import (
+	"context"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// BenchmarkPatterns are the patterns looked up by [BenchmarkParallelHits].
+// Caches that only serve hits from a snapshot can warm up with them first.
+var BenchmarkPatterns = []string{
+	`p([a-z]+)ch`,
+	`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`,
+	`^/api/v[0-9]+/`,
+	`\d{4}-\d{2}-\d{2}`,
+}
+
+// NamedCache is a cache compared by [BenchmarkParallelHits], with the name of
+// its sub-benchmark.
+type NamedCache struct {
+	Cache recache.Cache
+	Name  string
+}
+
+// BenchmarkParallelHits runs a sub-benchmark for each of the given caches
+// that looks up [BenchmarkPatterns] from many goroutines at once. After the
+// first lookup of each pattern every lookup is a hit, so the benchmarks
+// measure the cost of the lock, if any, taken on hits.
+func BenchmarkParallelHits(b *testing.B, caches ...NamedCache) {
+	b.Helper()
+
+	ctx := context.Background()
+
+	for _, bc := range caches {
+		bc := bc
+
+		b.Run(bc.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				var i int
+
+				for pb.Next() {
+					if _, err := bc.Cache.Get(ctx, BenchmarkPatterns[i%len(BenchmarkPatterns)], recache.DefaultFlag); err != nil {
+						b.Error(err)
+
+						return
+					}
+
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package recachetest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// FakeClock is a [recache.Clock] that only moves when told to, for testing
+// time-based behavior deterministically. It is safe for concurrent use.
+//
+// [recache.Clock]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Clock
+type FakeClock struct {
+	// now is the time the clock reports.
+	now time.Time
+
+	// mu is a mutex that protects access to now.
+	mu sync.Mutex
+}
+
+// Compile-time check to ensure FakeClock implements the recache.Clock
+// interface.
+var _ recache.Clock = (*FakeClock)(nil)
+
+// NewFakeClock returns a FakeClock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now: now,
+	}
+}
+
+// Now returns the time the clock is set to.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by the given duration, or backward if it is
+// negative.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Set sets the clock to the given time.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// NewClockFunc returns a new, empty cache with the given capacity that reads
+// the time for the timestamps of its entries from the given clock.
+type NewClockFunc func(capacity int, clock recache.Clock) recache.Cache
+
+// RunClock checks that caches returned by newCache read the creation and
+// access times of their entries from the clock they are given. The caches
+// must implement [recache.EntryLister].
+//
+// [recache.EntryLister]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#EntryLister
+func RunClock(t *testing.T, newCache NewClockFunc) {
+	t.Helper()
+
+	var (
+		start = time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+		clock = NewFakeClock(start)
+		cache = newCache(Capacity, clock)
+	)
+
+	lister, ok := cache.(recache.EntryLister)
+	if !ok {
+		t.Fatalf("%T does not implement recache.EntryLister", cache)
+	}
+
+	for _, pattern := range []string{`^a`, `^b`, `^a`} {
+		mustGet(t, cache, pattern, recache.DefaultFlag)
+		clock.Advance(time.Minute)
+	}
+
+	want := map[string]struct{ created, accessed time.Time }{
+		`^a`: {created: start, accessed: start.Add(2 * time.Minute)},
+		`^b`: {created: start.Add(time.Minute), accessed: start.Add(time.Minute)},
+	}
+
+	entries := lister.Entries()
+
+	if len(entries) != len(want) {
+		t.Fatalf("len(Entries()) = %d, want %d", len(entries), len(want))
+	}
+
+	for _, entry := range entries {
+		w, ok := want[entry.Pattern()]
+		if !ok {
+			t.Errorf("Entries() includes %q, which was never looked up", entry.Pattern())
+
+			continue
+		}
+
+		if !entry.Created().Equal(w.created) || !entry.Accessed().Equal(w.accessed) {
+			t.Errorf("%q: Created() = %v, Accessed() = %v, want %v and %v",
+				entry.Pattern(), entry.Created(), entry.Accessed(), w.created, w.accessed)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+// Package recachetest provides a conformance test suite for implementations of
+// the [recache.Cache] interface.
+//
+// Implementations run the suite from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		recachetest.Run(t, func(capacity int) recache.Cache {
+//			return mycache.New(capacity)
+//		})
+//	}
+//
+// The suite includes a concurrency stress test, so it is most useful when the
+// tests are run with the race detector enabled.
+//
+// [BenchmarkParallelHits] compares the cost of hits on several caches under
+// contention, for implementations that want to show they take less of a lock
+// than the caches they are meant to replace.
+//
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+package recachetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// Capacity is the capacity the suite asks the constructor for, unless a test
+// needs a different one.
+const Capacity int = 8
+
+// _stressGoroutines is the number of goroutines used by the concurrency stress
+// test.
+const _stressGoroutines int = 8
+
+// _stressIterations is the number of operations each goroutine performs in the
+// concurrency stress test.
+const _stressIterations int = 500
+
+// NewFunc returns a new, empty cache with the given capacity.
+type NewFunc func(capacity int) recache.Cache
+
+// Run runs the conformance test suite against caches returned by newCache.
+// Each test gets a fresh cache, and tests run in parallel.
+func Run(t *testing.T, newCache NewFunc) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, newCache NewFunc)
+	}{
+		{name: "Hit and miss", test: testHitMiss},
+		{name: "Same key", test: testSameKey},
+		{name: "Isolation", test: testIsolation},
+		{name: "Capacity never exceeded", test: testCapacity},
+		{name: "SetCapacity shrink", test: testShrink},
+		{name: "SetCapacity grow", test: testGrow},
+		{name: "SetCapacity invalid", test: testInvalidCapacity},
+		{name: "Clear", test: testClear},
+		{name: "Flags", test: testFlags},
+		{name: "Invalid pattern", test: testInvalidPattern},
+		{name: "Must invalid pattern", test: testMustInvalidPattern},
+		{name: "Concurrency", test: testConcurrency},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.test(t, newCache)
+		})
+	}
+}
+
+// pattern returns the i-th distinct pattern used to fill caches.
+func pattern(i int) string {
+	return fmt.Sprintf("^p%d$", i)
+}
+
+// mustGet calls Get and fails the test if it returns an error or a regular
+// expression for another pattern.
+func mustGet(t *testing.T, cache recache.Cache, pattern string, flag recache.Flag) *regexp.Regexp {
+	t.Helper()
+
+	regex, err := cache.Get(context.Background(), pattern, flag)
+	if err != nil {
+		t.Fatalf("Get(%q, %s) error = %v", pattern, flag, err)
+	}
+
+	if regex == nil {
+		t.Fatalf("Get(%q, %s) = nil", pattern, flag)
+	}
+
+	if regex.String() != pattern {
+		t.Fatalf("Get(%q, %s).String() = %q", pattern, flag, regex.String())
+	}
+
+	return regex
+}
+
+func testHitMiss(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	if got := cache.Size(); got != 0 {
+		t.Fatalf("Size() of new cache = %d, want 0", got)
+	}
+
+	regex := mustGet(t, cache, `^[a-z]+\[[0-9]+\]$`, recache.DefaultFlag)
+
+	if !regex.MatchString("adam[23]") {
+		t.Errorf("MatchString(%q) = false, want true", "adam[23]")
+	}
+
+	if regex.MatchString("Job[48]") {
+		t.Errorf("MatchString(%q) = true, want false", "Job[48]")
+	}
+
+	if got := cache.Size(); got != 1 {
+		t.Errorf("Size() after miss = %d, want 1", got)
+	}
+
+	mustGet(t, cache, `^[a-z]+\[[0-9]+\]$`, recache.DefaultFlag)
+
+	if got := cache.Size(); got != 1 {
+		t.Errorf("Size() after hit = %d, want 1", got)
+	}
+}
+
+func testSameKey(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	first := mustGet(t, cache, `(\w+)@(\w+)\.com`, recache.DefaultFlag)
+	second := mustGet(t, cache, `(\w+)@(\w+)\.com`, recache.DefaultFlag)
+
+	if first == second {
+		t.Error("Get() returned the same *regexp.Regexp twice, want a copy")
+	}
+
+	if first.String() != second.String() {
+		t.Errorf("Get() patterns = %q and %q, want equal", first.String(), second.String())
+	}
+
+	if first.NumSubexp() != second.NumSubexp() {
+		t.Errorf("Get() NumSubexp = %d and %d, want equal", first.NumSubexp(), second.NumSubexp())
+	}
+}
+
+func testIsolation(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	first := mustGet(t, cache, "a+?", recache.DefaultFlag)
+	first.Longest()
+
+	if got := first.FindString("aaa"); got != "aaa" {
+		t.Fatalf("FindString() after Longest() = %q, want %q", got, "aaa")
+	}
+
+	second := mustGet(t, cache, "a+?", recache.DefaultFlag)
+
+	if got := second.FindString("aaa"); got != "a" {
+		t.Errorf("FindString() on later Get() = %q, want %q", got, "a")
+	}
+}
+
+func testCapacity(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	if got := cache.Capacity(); got != Capacity {
+		t.Fatalf("Capacity() = %d, want %d", got, Capacity)
+	}
+
+	for i := 0; i < Capacity*3; i++ {
+		mustGet(t, cache, pattern(i), recache.DefaultFlag)
+
+		if got := cache.Size(); got > Capacity {
+			t.Fatalf("Size() after %d patterns = %d, want at most %d", i+1, got, Capacity)
+		}
+	}
+
+	if got := cache.Size(); got != Capacity {
+		t.Errorf("Size() of full cache = %d, want %d", got, Capacity)
+	}
+}
+
+func testShrink(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	for i := 0; i < Capacity; i++ {
+		mustGet(t, cache, pattern(i), recache.DefaultFlag)
+	}
+
+	const capacity = Capacity / 2
+
+	if err := cache.SetCapacity(capacity); err != nil {
+		t.Fatalf("SetCapacity(%d) error = %v", capacity, err)
+	}
+
+	if got := cache.Capacity(); got != capacity {
+		t.Errorf("Capacity() = %d, want %d", got, capacity)
+	}
+
+	if got := cache.Size(); got > capacity {
+		t.Errorf("Size() after shrinking = %d, want at most %d", got, capacity)
+	}
+
+	for i := 0; i < Capacity; i++ {
+		mustGet(t, cache, pattern(Capacity+i), recache.DefaultFlag)
+
+		if got := cache.Size(); got > capacity {
+			t.Fatalf("Size() after shrinking and adding %d patterns = %d, want at most %d", i+1, got, capacity)
+		}
+	}
+}
+
+func testGrow(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	for i := 0; i < Capacity; i++ {
+		mustGet(t, cache, pattern(i), recache.DefaultFlag)
+	}
+
+	const capacity = Capacity * 2
+
+	if err := cache.SetCapacity(capacity); err != nil {
+		t.Fatalf("SetCapacity(%d) error = %v", capacity, err)
+	}
+
+	if got := cache.Capacity(); got != capacity {
+		t.Errorf("Capacity() = %d, want %d", got, capacity)
+	}
+
+	if got := cache.Size(); got != Capacity {
+		t.Errorf("Size() after growing = %d, want %d", got, Capacity)
+	}
+
+	for i := Capacity; i < capacity; i++ {
+		mustGet(t, cache, pattern(i), recache.DefaultFlag)
+	}
+
+	if got := cache.Size(); got != capacity {
+		t.Errorf("Size() after filling grown cache = %d, want %d", got, capacity)
+	}
+}
+
+func testInvalidCapacity(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	for _, capacity := range []int{0, -1} {
+		if err := cache.SetCapacity(capacity); !errors.Is(err, recache.ErrInvalidCapacity) {
+			t.Errorf("SetCapacity(%d) error = %v, want %v", capacity, err, recache.ErrInvalidCapacity)
+		}
+	}
+
+	if got := cache.Capacity(); got != Capacity {
+		t.Errorf("Capacity() after invalid SetCapacity() = %d, want %d", got, Capacity)
+	}
+}
+
+func testClear(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	for i := 0; i < Capacity; i++ {
+		mustGet(t, cache, pattern(i), recache.DefaultFlag)
+	}
+
+	cache.Clear()
+
+	if got := cache.Size(); got != 0 {
+		t.Errorf("Size() after Clear() = %d, want 0", got)
+	}
+
+	if got := cache.Capacity(); got != Capacity {
+		t.Errorf("Capacity() after Clear() = %d, want %d", got, Capacity)
+	}
+
+	mustGet(t, cache, pattern(0), recache.DefaultFlag)
+
+	if got := cache.Size(); got != 1 {
+		t.Errorf("Size() after Clear() and Get() = %d, want 1", got)
+	}
+}
+
+func testFlags(t *testing.T, newCache NewFunc) {
+	tests := []struct {
+		flag recache.Flag
+		want string
+	}{
+		{flag: recache.DefaultFlag, want: "a"},
+		{flag: recache.FlagPOSIX, want: "ab"},
+		{flag: recache.FlagMust, want: "a"},
+		{flag: recache.FlagMustPOSIX, want: "ab"},
+	}
+
+	cache := newCache(Capacity)
+
+	// Leftmost-first and leftmost-longest matching disagree on this pattern,
+	// which tells the flags apart.
+	for _, tt := range tests {
+		regex := mustGet(t, cache, "a|ab", tt.flag)
+
+		if got := regex.FindString("ab"); got != tt.want {
+			t.Errorf("Get(%q, %s).FindString(%q) = %q, want %q", "a|ab", tt.flag, "ab", got, tt.want)
+		}
+	}
+
+	if got := cache.Size(); got != len(tests) {
+		t.Errorf("Size() after one pattern with %d flags = %d, want %d", len(tests), got, len(tests))
+	}
+
+	if _, err := cache.Get(context.Background(), `\d`, recache.FlagPOSIX); err == nil {
+		t.Errorf("Get(%q, %s) error = nil, want error", `\d`, recache.FlagPOSIX)
+	}
+}
+
+func testInvalidPattern(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	for i := 0; i < 2; i++ {
+		regex, err := cache.Get(context.Background(), "a(b", recache.DefaultFlag)
+		if err == nil {
+			t.Fatalf("Get(%q) error = nil, want error", "a(b")
+		}
+
+		if regex != nil {
+			t.Errorf("Get(%q) = %v, want nil", "a(b", regex)
+		}
+	}
+
+	if got := cache.Size(); got != 0 {
+		t.Errorf("Size() after invalid pattern = %d, want 0", got)
+	}
+}
+
+func testMustInvalidPattern(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Get(%q, %s) did not panic", "a(b", recache.FlagMust)
+			}
+		}()
+
+		cache.Get(context.Background(), "a(b", recache.FlagMust) //nolint:errcheck // the call panics
+	}()
+
+	if got := cache.Size(); got != 0 {
+		t.Errorf("Size() after invalid pattern = %d, want 0", got)
+	}
+
+	mustGet(t, cache, pattern(0), recache.DefaultFlag)
+}
+
+func testConcurrency(t *testing.T, newCache NewFunc) {
+	cache := newCache(Capacity)
+
+	var (
+		wg     sync.WaitGroup
+		errsMu sync.Mutex
+		errs   []error
+	)
+
+	report := func(err error) {
+		errsMu.Lock()
+		defer errsMu.Unlock()
+
+		errs = append(errs, err)
+	}
+
+	for g := 0; g < _stressGoroutines; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < _stressIterations; i++ {
+				p := pattern((g*7 + i) % (Capacity * 3))
+
+				regex, err := cache.Get(context.Background(), p, recache.DefaultFlag)
+				if err != nil {
+					report(fmt.Errorf("Get(%q) error = %w", p, err))
+
+					return
+				}
+
+				if regex.String() != p {
+					report(fmt.Errorf("Get(%q).String() = %q", p, regex.String()))
+
+					return
+				}
+
+				switch i % 100 {
+				case 10:
+					_ = cache.Size()
+				case 50:
+					cache.Clear()
+				case 90:
+					cache.SetCapacity(Capacity/2 + i%Capacity) //nolint:errcheck // the capacity is always valid
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		t.Error(err)
+	}
+
+	if size, capacity := cache.Size(), cache.Capacity(); size > capacity {
+		t.Errorf("Size() after stress = %d, want at most Capacity() = %d", size, capacity)
+	}
+}
@@ -0,0 +1,18 @@
+// Command recachevet reports regular expressions compiled in hot paths, where
+// a recache.Cache or recache.Lazy would avoid recompiling them.
+//
+// Usage:
+//
+//	recachevet [-fix] [packages]
+//
+// Run with -help for the full list of flags.
+package main
+
+import (
+	"git.sr.ht/~jamesponddotco/recache-go/recachevet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(recachevet.Analyzer)
+}
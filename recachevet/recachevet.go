@@ -0,0 +1,481 @@
+// Package recachevet defines an [Analyzer] that reports regular expressions
+// compiled in hot paths, where a [recache.Cache] or [recache.Lazy] would avoid
+// recompiling them.
+//
+// The package is a module of its own, so that depending on recache does not
+// pull in golang.org/x/tools or raise the minimum Go version to the one it
+// requires.
+//
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+// [recache.Lazy]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Lazy
+package recachevet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Doc is the documentation of the analyzer.
+const Doc = `report regular expressions compiled in hot paths
+
+The recachevet analyzer reports calls to regexp.Compile, regexp.MustCompile,
+regexp.MatchString and similar functions inside functions, loops, and HTTP
+handlers, which recompile the pattern on every call. It suggests replacing
+them with recache.Cache.Get, recache.Lazy, or the package-level functions of
+recache, and offers suggested fixes where the replacement is safe.
+
+It also reports package-level regexp.MustCompile variables, which are compiled
+at init time whether they are used or not, and could be recache.Lazy instead.`
+
+// Analyzer reports regular expressions compiled in hot paths.
+var Analyzer = &analysis.Analyzer{
+	Name:     "recachevet",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const (
+	_regexpPath  string = "regexp"
+	_recachePath string = "git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// _compileFuncs maps the regexp functions that compile a pattern to the
+// recache flag with the same behavior.
+var _compileFuncs = map[string]string{
+	"Compile":          "DefaultFlag",
+	"CompilePOSIX":     "FlagPOSIX",
+	"MustCompile":      "FlagMust",
+	"MustCompilePOSIX": "FlagMustPOSIX",
+}
+
+// _matchFuncs holds the regexp functions that compile a pattern and match it
+// in one call. The recache package has drop-in replacements for all of them.
+var _matchFuncs = map[string]bool{
+	"Match":       true,
+	"MatchString": true,
+	"MatchReader": true,
+}
+
+// _lazyMethods holds the methods of *regexp.Regexp that recache.LazyRegexp
+// also has.
+var _lazyMethods = map[string]bool{
+	"Expand": true, "ExpandString": true, "Find": true, "FindAll": true,
+	"FindAllIndex": true, "FindAllString": true, "FindAllStringIndex": true,
+	"FindAllStringSubmatch": true, "FindAllStringSubmatchIndex": true,
+	"FindAllSubmatch": true, "FindAllSubmatchIndex": true, "FindIndex": true,
+	"FindReaderIndex": true, "FindReaderSubmatchIndex": true, "FindString": true,
+	"FindStringIndex": true, "FindStringSubmatch": true,
+	"FindStringSubmatchIndex": true, "FindSubmatch": true,
+	"FindSubmatchIndex": true, "LiteralPrefix": true, "MarshalText": true,
+	"Match": true, "MatchReader": true, "MatchString": true, "NumSubexp": true,
+	"ReplaceAll": true, "ReplaceAllFunc": true, "ReplaceAllLiteral": true,
+	"ReplaceAllLiteralString": true, "ReplaceAllString": true,
+	"ReplaceAllStringFunc": true, "Split": true, "String": true,
+	"SubexpIndex": true, "SubexpNames": true,
+}
+
+// finding is a call to a regexp function that recachevet reports.
+type finding struct {
+	call     *ast.CallExpr
+	selector *ast.SelectorExpr
+	name     string
+	message  string
+	file     *ast.File
+	fix      func(f *finding) []analysis.TextEdit
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ins, ok := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected inspector type %T", pass.ResultOf[inspect.Analyzer])
+	}
+
+	var (
+		findings []*finding
+		// uses maps the selector expressions of each file referring to the
+		// regexp package, such as functions and types, to whether a
+		// suggested fix rewrites them.
+		uses = make(map[*ast.File]map[*ast.SelectorExpr]bool)
+		file *ast.File
+	)
+
+	ins.WithStack([]ast.Node{(*ast.File)(nil), (*ast.SelectorExpr)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+
+		if f, ok := n.(*ast.File); ok {
+			file = f
+			uses[file] = make(map[*ast.SelectorExpr]bool)
+
+			return true
+		}
+
+		sel, _ := n.(*ast.SelectorExpr)
+		if !isRegexpPackage(pass.TypesInfo, sel) {
+			return true
+		}
+
+		uses[file][sel] = false
+
+		name, ok := regexpFunc(pass.TypesInfo, sel)
+		if !ok {
+			return true
+		}
+
+		call, ok := stack[len(stack)-2].(*ast.CallExpr)
+		if !ok || call.Fun != sel || len(call.Args) == 0 {
+			return true
+		}
+
+		if f := inspectCall(pass, stack, call, sel, name); f != nil {
+			f.file = file
+			uses[file][sel] = f.fix != nil
+			findings = append(findings, f)
+		}
+
+		return true
+	})
+
+	for _, f := range findings {
+		diag := analysis.Diagnostic{
+			Pos:     f.call.Pos(),
+			End:     f.call.End(),
+			Message: f.message,
+		}
+
+		// A fix is only safe if the file keeps using the regexp package
+		// afterwards, so applying it does not leave an unused import.
+		if f.fix != nil && keepsRegexp(uses[f.file]) {
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Use recache instead of regexp",
+				TextEdits: f.fix(f),
+			}}
+		}
+
+		pass.Report(diag)
+	}
+
+	return nil, nil //nolint:nilnil // the analyzer has no result
+}
+
+// inspectCall returns a finding for a call to a regexp function, or nil if
+// the call is not worth reporting.
+func inspectCall(pass *analysis.Pass, stack []ast.Node, call *ast.CallExpr, sel *ast.SelectorExpr, name string) *finding {
+	where, inFunc := location(pass.TypesInfo, stack)
+
+	if !inFunc {
+		return packageLevel(pass, stack, call, sel, name)
+	}
+
+	f := &finding{
+		call:     call,
+		selector: sel,
+		name:     name,
+	}
+
+	constant := pass.TypesInfo.Types[call.Args[0]].Value != nil
+
+	switch {
+	case _matchFuncs[name]:
+		f.message = fmt.Sprintf("regexp.%s recompiles the pattern on every call %s; use recache.%s instead", name, where, name)
+		f.fix = func(f *finding) []analysis.TextEdit {
+			return rewrite(f.file, f.selector, "recache."+f.name)
+		}
+	case constant:
+		f.message = fmt.Sprintf("regexp.%s compiles a constant pattern on every call %s; use a package-level recache.Lazy variable instead", name, where)
+	default:
+		f.message = fmt.Sprintf("regexp.%s compiles a dynamic pattern on every call %s; use recache.Cache.Get to reuse compiled regular expressions", name, where)
+	}
+
+	return f
+}
+
+// packageLevel returns a finding for a package-level variable initialized
+// with regexp.MustCompile or regexp.MustCompilePOSIX, or nil if the call is
+// not one.
+func packageLevel(pass *analysis.Pass, stack []ast.Node, call *ast.CallExpr, sel *ast.SelectorExpr, name string) *finding {
+	if name != "MustCompile" && name != "MustCompilePOSIX" {
+		return nil
+	}
+
+	spec, ok := stack[len(stack)-3].(*ast.ValueSpec)
+	if !ok {
+		return nil
+	}
+
+	f := &finding{
+		call:     call,
+		selector: sel,
+		name:     name,
+		message:  fmt.Sprintf("package-level regexp.%s compiles the pattern at init time; use recache.Lazy to compile it on first use", name),
+	}
+
+	if len(spec.Names) != 1 || len(spec.Values) != 1 || spec.Type != nil || !onlyLazyUses(pass, spec.Names[0]) {
+		return f
+	}
+
+	f.fix = func(f *finding) []analysis.TextEdit {
+		edits := rewrite(f.file, f.selector, "recache.Lazy")
+
+		return append(edits, analysis.TextEdit{
+			Pos:     f.call.Rparen,
+			End:     f.call.Rparen,
+			NewText: []byte(", recache." + _compileFuncs[f.name]),
+		})
+	}
+
+	return f
+}
+
+// location describes where the call at the top of the stack is, and reports
+// whether it is inside a function at all.
+func location(info *types.Info, stack []ast.Node) (string, bool) {
+	inLoop := false
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch node := stack[i].(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			inLoop = true
+		case *ast.FuncLit:
+			return describe(info, node.Type, inLoop), true
+		case *ast.FuncDecl:
+			return describe(info, node.Type, inLoop), true
+		}
+	}
+
+	return "", false
+}
+
+// describe returns a description of a call site given the type of its
+// enclosing function and whether it is inside a loop.
+func describe(info *types.Info, fn *ast.FuncType, inLoop bool) string {
+	switch {
+	case inLoop:
+		return "inside a loop"
+	case isHandler(info, fn):
+		return "of this HTTP handler"
+	default:
+		return "of this function"
+	}
+}
+
+// isHandler reports whether the given function type has the signature of an
+// http.HandlerFunc.
+func isHandler(info *types.Info, fn *ast.FuncType) bool {
+	if fn.Params == nil || len(fn.Params.List) == 0 {
+		return false
+	}
+
+	var params []types.Type
+
+	for _, field := range fn.Params.List {
+		typ := info.TypeOf(field.Type)
+
+		params = append(params, typ)
+
+		for i := 1; i < len(field.Names); i++ {
+			params = append(params, typ)
+		}
+	}
+
+	if len(params) != 2 {
+		return false
+	}
+
+	return isNamed(params[0], "net/http", "ResponseWriter") && isNamed(params[1], "net/http", "*Request")
+}
+
+// isNamed reports whether typ is the named type from the given package. A
+// leading asterisk in name matches a pointer to the named type.
+func isNamed(typ types.Type, path, name string) bool {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		if name[0] != '*' {
+			return false
+		}
+
+		typ, name = ptr.Elem(), name[1:]
+	}
+
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+
+	return obj.Pkg() != nil && obj.Pkg().Path() == path && obj.Name() == name
+}
+
+// onlyLazyUses reports whether the unexported variable with the given name is
+// only ever used to call methods recache.LazyRegexp also has.
+func onlyLazyUses(pass *analysis.Pass, ident *ast.Ident) bool {
+	obj := pass.TypesInfo.Defs[ident]
+	if obj == nil || obj.Exported() {
+		return false
+	}
+
+	selectors := make(map[*ast.Ident]bool)
+
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && _lazyMethods[sel.Sel.Name] {
+					selectors[x] = true
+				}
+			}
+
+			return true
+		})
+	}
+
+	for ident, used := range pass.TypesInfo.Uses {
+		if used == obj && !selectors[ident] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isRegexpPackage reports whether the selector expression refers to a member
+// of the regexp package, such as regexp.QuoteMeta or regexp.Regexp.
+func isRegexpPackage(info *types.Info, sel *ast.SelectorExpr) bool {
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	pkg, ok := info.Uses[x].(*types.PkgName)
+
+	return ok && pkg.Imported().Path() == _regexpPath
+}
+
+// regexpFunc returns the name of the regexp package function the selector
+// refers to, if it is one that compiles a pattern.
+func regexpFunc(info *types.Info, sel *ast.SelectorExpr) (string, bool) {
+	fn, ok := typeutil.Callee(info, &ast.CallExpr{Fun: sel}).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != _regexpPath {
+		return "", false
+	}
+
+	if sig, ok := fn.Type().(*types.Signature); !ok || sig.Recv() != nil {
+		return "", false
+	}
+
+	if _, ok := _compileFuncs[fn.Name()]; !ok && !_matchFuncs[fn.Name()] {
+		return "", false
+	}
+
+	return fn.Name(), true
+}
+
+// keepsRegexp reports whether at least one use of the regexp package in a
+// file, of any of its functions or types, is left untouched by suggested
+// fixes.
+func keepsRegexp(uses map[*ast.SelectorExpr]bool) bool {
+	for _, fixed := range uses {
+		if !fixed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rewrite returns the edits that replace the given selector expression with
+// replacement, importing recache into the file if needed.
+func rewrite(file *ast.File, sel *ast.SelectorExpr, replacement string) []analysis.TextEdit {
+	edits := []analysis.TextEdit{{
+		Pos:     sel.Pos(),
+		End:     sel.End(),
+		NewText: []byte(replacement),
+	}}
+
+	if edit, ok := addImport(file); ok {
+		edits = append(edits, edit)
+	}
+
+	return edits
+}
+
+// addImport returns the edit that imports recache into the file, or false if
+// the file already imports it or does not import regexp. The import goes
+// after the last import of the declaration importing regexp, in a group of its
+// own unless that group already holds packages outside the standard library.
+func addImport(file *ast.File) (analysis.TextEdit, bool) {
+	var regexpSpec *ast.ImportSpec
+
+	for _, spec := range file.Imports {
+		switch importPath(spec) {
+		case _recachePath:
+			return analysis.TextEdit{}, false
+		case _regexpPath:
+			regexpSpec = spec
+		}
+	}
+
+	if regexpSpec == nil {
+		return analysis.TextEdit{}, false
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT || regexpSpec.Pos() < gen.Pos() || gen.End() < regexpSpec.End() {
+			continue
+		}
+
+		if !gen.Lparen.IsValid() {
+			return analysis.TextEdit{
+				Pos:     gen.End(),
+				End:     gen.End(),
+				NewText: []byte("\n\nimport " + strconv.Quote(_recachePath)),
+			}, true
+		}
+
+		last, _ := gen.Specs[len(gen.Specs)-1].(*ast.ImportSpec)
+
+		text := "\n\n\t" + strconv.Quote(_recachePath)
+		if !isStandard(importPath(last)) {
+			text = "\n\t" + strconv.Quote(_recachePath)
+		}
+
+		return analysis.TextEdit{
+			Pos:     last.End(),
+			End:     last.End(),
+			NewText: []byte(text),
+		}, true
+	}
+
+	return analysis.TextEdit{}, false
+}
+
+// importPath returns the unquoted path of an import, or an empty string if it
+// is malformed.
+func importPath(spec *ast.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return ""
+	}
+
+	return path
+}
+
+// isStandard reports whether the import path belongs to the standard library,
+// using the same rule as goimports: only paths outside it have a dot in their
+// first element.
+func isStandard(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,14 @@
+package recachevet_test
+
+import (
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go/recachevet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	t.Parallel()
+
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), recachevet.Analyzer, "a")
+}
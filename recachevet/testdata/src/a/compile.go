@@ -0,0 +1,18 @@
+package a
+
+import "regexp"
+
+func constant(s string) bool {
+	re := regexp.MustCompile(`^a+$`) // want `regexp.MustCompile compiles a constant pattern on every call of this function`
+
+	return re.MatchString(s)
+}
+
+func dynamic(pattern, s string) (bool, error) {
+	re, err := regexp.Compile(pattern) // want `regexp.Compile compiles a dynamic pattern on every call of this function`
+	if err != nil {
+		return false, err
+	}
+
+	return re.MatchString(s), nil
+}
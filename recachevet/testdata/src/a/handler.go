@@ -0,0 +1,16 @@
+package a
+
+import (
+	"net/http"
+	"regexp"
+
+	"example.com/log"
+)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	ok, _ := regexp.MatchString(`^/api/`, r.URL.Path) // want `regexp.MatchString recompiles the pattern on every call of this HTTP handler; use recache.MatchString instead`
+	if !ok {
+		log.Print(regexp.QuoteMeta(r.URL.Path))
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
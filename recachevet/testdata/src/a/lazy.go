@@ -0,0 +1,19 @@
+package a
+
+import (
+	"regexp"
+)
+
+var _email = regexp.MustCompile(`^[a-z]+@[a-z]+\.[a-z]{2,}$`) // want `package-level regexp.MustCompile compiles the pattern at init time`
+
+var Exported = regexp.MustCompile(`^exported$`) // want `package-level regexp.MustCompile compiles the pattern at init time`
+
+var _escaped = regexp.MustCompile(`^escaped$`) // want `package-level regexp.MustCompile compiles the pattern at init time`
+
+func useVars(s string) bool {
+	sink(_escaped)
+
+	return _email.MatchString(s)
+}
+
+func sink(_ *regexp.Regexp) {}
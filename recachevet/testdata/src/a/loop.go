@@ -0,0 +1,22 @@
+package a
+
+import "regexp"
+
+func loop(lines []string) int {
+	var n int
+
+	for _, line := range lines {
+		if ok, _ := regexp.MatchString(`^\d+$`, line); ok { // want `regexp.MatchString recompiles the pattern on every call inside a loop; use recache.MatchString instead`
+			n++
+		}
+	}
+
+	return n
+}
+
+// literal keeps the file using the regexp package once the loop is rewritten.
+func literal(re *regexp.Regexp) string {
+	prefix, _ := re.LiteralPrefix()
+
+	return prefix
+}
@@ -0,0 +1,17 @@
+package a
+
+import (
+	"regexp"
+	"strings"
+)
+
+var _words = regexp.MustCompilePOSIX(`[[:alpha:]]+`) // want `package-level regexp.MustCompilePOSIX compiles the pattern at init time`
+
+func words(s string) []string {
+	return _words.FindAllString(strings.ToLower(s), -1)
+}
+
+// quote keeps the file using the regexp package once _words is rewritten.
+func quote(s string) string {
+	return regexp.QuoteMeta(s)
+}
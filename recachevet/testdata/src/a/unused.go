@@ -0,0 +1,11 @@
+package a
+
+import "regexp"
+
+// only has no suggested fix, as rewriting its only use of the regexp package
+// would leave the import unused.
+func only(s string) bool {
+	ok, _ := regexp.MatchString(`^only$`, s) // want `regexp.MatchString recompiles the pattern on every call of this function; use recache.MatchString instead`
+
+	return ok
+}
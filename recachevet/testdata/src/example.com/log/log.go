@@ -0,0 +1,3 @@
+package log
+
+func Print(v ...any) {}
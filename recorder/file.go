@@ -0,0 +1,200 @@
+package recorder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	// DefaultMaxSize is the default size, in bytes, after which trace files
+	// are rotated.
+	DefaultMaxSize int64 = 64 << 20
+
+	// DefaultMaxBackups is the default number of rotated trace files kept.
+	DefaultMaxBackups int = 5
+)
+
+// File is a trace file that is rotated once it grows past a maximum size.
+//
+// When the file is rotated, it is renamed to path.1, the previous path.1 is
+// renamed to path.2, and so on, and the oldest backup beyond the maximum is
+// removed. Writes are buffered, so the file must be closed, or flushed, for
+// the latest records to reach the disk.
+type File struct {
+	// f is the file currently written to.
+	f *os.File
+
+	// w buffers writes to f.
+	w *bufio.Writer
+
+	// path is the path of the file currently written to.
+	path string
+
+	// maxSize is the size after which the file is rotated.
+	maxSize int64
+
+	// size is the size of the file currently written to.
+	size int64
+
+	// maxBackups is the number of rotated files kept.
+	maxBackups int
+
+	// mu is a mutex that protects access to the file.
+	mu sync.Mutex
+}
+
+// NewFile opens the trace file at the given path for appending, creating it if
+// needed. If maxSize is less than 1, DefaultMaxSize is used instead, and if
+// maxBackups is less than 0, DefaultMaxBackups is used instead.
+func NewFile(path string, maxSize int64, maxBackups int) (*File, error) {
+	if maxSize < 1 {
+		maxSize = DefaultMaxSize
+	}
+
+	if maxBackups < 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	f := &File{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write writes p to the file, rotating it first if p would make it grow past
+// its maximum size. Records are never split across files, as the Recorder
+// writes each one in a single call.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return 0, fmt.Errorf("%w", os.ErrClosed)
+	}
+
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.w.Write(p)
+	f.size += int64(n)
+
+	if err != nil {
+		return n, fmt.Errorf("%w", err)
+	}
+
+	return n, nil
+}
+
+// Flush writes any buffered data to the file.
+func (f *File) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return fmt.Errorf("%w", os.ErrClosed)
+	}
+
+	if err := f.w.Flush(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// Close flushes any buffered data and closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return fmt.Errorf("%w", os.ErrClosed)
+	}
+
+	return f.close()
+}
+
+// open opens the file at f.path for appending. The caller must hold the lock,
+// if the file is shared.
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("%w", err)
+	}
+
+	f.f = file
+	f.w = bufio.NewWriter(file)
+	f.size = info.Size()
+
+	return nil
+}
+
+// close flushes and closes the current file. The caller must hold the lock.
+func (f *File) close() error {
+	flushErr := f.w.Flush()
+	closeErr := f.f.Close()
+
+	f.f = nil
+	f.w = nil
+
+	if err := errors.Join(flushErr, closeErr); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// rotate closes the current file, shifts the backups, and opens a new file.
+// The caller must hold the lock.
+func (f *File) rotate() error {
+	if err := f.close(); err != nil {
+		return err
+	}
+
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w", err)
+		}
+
+		return f.open()
+	}
+
+	for i := f.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(backupPath(f.path, i), backupPath(f.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w", err)
+		}
+	}
+
+	if err := os.Rename(f.path, backupPath(f.path, 1)); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return f.open()
+}
+
+// backupPath returns the path of the n-th backup of the file at path.
+func backupPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
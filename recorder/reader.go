@@ -0,0 +1,147 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// _maxLineSize is the size of the longest record a Reader accepts.
+const _maxLineSize int = 1 << 20
+
+// Reader reads records from a trace.
+type Reader struct {
+	// scanner splits the trace into lines.
+	scanner *bufio.Scanner
+
+	// closers are closed by Close.
+	closers []io.Closer
+
+	// line is the number of the last line read.
+	line int
+}
+
+// NewReader returns a Reader that reads records from r.
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, _maxLineSize)
+
+	return &Reader{
+		scanner: scanner,
+	}
+}
+
+// Open returns a Reader that reads the trace file at the given path along with
+// its rotated backups, oldest first, so records come out in the order they
+// were recorded.
+func Open(path string) (*Reader, error) {
+	var paths []string
+
+	for n := 1; ; n++ {
+		if _, err := os.Stat(backupPath(path, n)); err != nil {
+			break
+		}
+
+		paths = append(paths, backupPath(path, n))
+	}
+
+	for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+		paths[i], paths[j] = paths[j], paths[i]
+	}
+
+	paths = append(paths, path)
+
+	var (
+		readers = make([]io.Reader, 0, len(paths))
+		closers = make([]io.Closer, 0, len(paths))
+	)
+
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p != path {
+				continue
+			}
+
+			for _, c := range closers {
+				c.Close()
+			}
+
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		readers = append(readers, f)
+		closers = append(closers, f)
+	}
+
+	r := NewReader(io.MultiReader(readers...))
+	r.closers = closers
+
+	return r, nil
+}
+
+// Read returns the next record in the trace, skipping blank lines. It returns
+// io.EOF once there are no more records.
+func (r *Reader) Read() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		if len(r.scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec Record
+
+		if err := json.Unmarshal(r.scanner.Bytes(), &rec); err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		return rec, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, fmt.Errorf("%w", err)
+	}
+
+	return Record{}, io.EOF
+}
+
+// ReadAll returns every remaining record in the trace.
+func (r *Reader) ReadAll() ([]Record, error) {
+	var records []Record
+
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, rec)
+	}
+}
+
+// Close closes the files opened by Open. It does nothing for readers returned
+// by NewReader.
+func (r *Reader) Close() error {
+	var errs []error
+
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+
+	r.closers = nil
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
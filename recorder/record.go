@@ -0,0 +1,188 @@
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/xstd-go/xerrors"
+)
+
+const (
+	// ErrUnknownFlag is returned when a record has an unrecognized flag.
+	ErrUnknownFlag xerrors.Error = "unknown flag"
+
+	// ErrUnknownOutcome is returned when a record has an unrecognized outcome.
+	ErrUnknownOutcome xerrors.Error = "unknown outcome"
+)
+
+// _flags lists every flag a record can carry.
+var _flags = [...]recache.Flag{
+	recache.DefaultFlag,
+	recache.FlagPOSIX,
+	recache.FlagMust,
+	recache.FlagMustPOSIX,
+}
+
+// Outcome is the result of a recorded lookup.
+type Outcome int
+
+const (
+	// OutcomeUnknown means the wrapped cache does not report whether lookups
+	// hit it. See [recache.Observe].
+	//
+	// [recache.Observe]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Observe
+	OutcomeUnknown Outcome = iota
+
+	// OutcomeHit means the regular expression was found in the cache.
+	OutcomeHit
+
+	// OutcomeMiss means the regular expression was compiled and added to the
+	// cache.
+	OutcomeMiss
+
+	// OutcomeError means the lookup returned an error, usually because the
+	// pattern failed to compile.
+	OutcomeError
+)
+
+// String returns a string representation of the outcome.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeHit:
+		return "hit"
+	case OutcomeMiss:
+		return "miss"
+	case OutcomeError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Record is a single lookup recorded from a cache.
+type Record struct {
+	// Time is when the lookup started.
+	Time time.Time
+
+	// Key identifies the pattern and flag. It is the key generated by
+	// [recache.Key], so it is the same for every lookup of the same pattern
+	// and flag, but does not reveal the pattern.
+	//
+	// [recache.Key]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Key
+	Key string
+
+	// Pattern is the regular expression looked up, or empty if the recorder
+	// was not configured to record patterns.
+	Pattern string
+
+	// Flag is the flag the pattern was compiled with.
+	Flag recache.Flag
+
+	// Outcome is the result of the lookup.
+	Outcome Outcome
+
+	// Compile is the time spent compiling the regular expression, or zero on
+	// hits and when the outcome is unknown.
+	Compile time.Duration
+
+	// Latency is the time the whole lookup took.
+	Latency time.Duration
+}
+
+// record is the JSON representation of a Record.
+type record struct {
+	Time    time.Time `json:"time"`
+	Key     string    `json:"key"`
+	Pattern string    `json:"pattern,omitempty"`
+	Flag    string    `json:"flag"`
+	Outcome string    `json:"outcome"`
+	Compile int64     `json:"compile_ns,omitempty"`
+	Latency int64     `json:"latency_ns"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r Record) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(record{
+		Time:    r.Time,
+		Key:     r.Key,
+		Pattern: r.Pattern,
+		Flag:    r.Flag.String(),
+		Outcome: r.Outcome.String(),
+		Compile: int64(r.Compile),
+		Latency: int64(r.Latency),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return data, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var rec record
+
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	flag, ok := parseFlag(rec.Flag)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownFlag, rec.Flag)
+	}
+
+	outcome, ok := parseOutcome(rec.Outcome)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownOutcome, rec.Outcome)
+	}
+
+	*r = Record{
+		Time:    rec.Time,
+		Key:     rec.Key,
+		Pattern: rec.Pattern,
+		Flag:    flag,
+		Outcome: outcome,
+		Compile: time.Duration(rec.Compile),
+		Latency: time.Duration(rec.Latency),
+	}
+
+	if r.Key == "" && r.Pattern != "" {
+		r.Key = recache.Key(r.Pattern, r.Flag)
+	}
+
+	return nil
+}
+
+// parseFlag returns the flag with the given string representation. An empty
+// string is the default flag.
+func parseFlag(s string) (recache.Flag, bool) {
+	if s == "" {
+		return recache.DefaultFlag, true
+	}
+
+	for _, flag := range _flags {
+		if flag.String() == s {
+			return flag, true
+		}
+	}
+
+	return recache.DefaultFlag, false
+}
+
+// parseOutcome returns the outcome with the given string representation. An
+// empty string is an unknown outcome.
+func parseOutcome(s string) (Outcome, bool) {
+	if s == "" {
+		return OutcomeUnknown, true
+	}
+
+	for _, outcome := range [...]Outcome{OutcomeUnknown, OutcomeHit, OutcomeMiss, OutcomeError} {
+		if outcome.String() == s {
+			return outcome, true
+		}
+	}
+
+	return OutcomeUnknown, false
+}
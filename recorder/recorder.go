@@ -0,0 +1,252 @@
+// Package recorder implements a [recache.Cache] decorator that records every
+// lookup to a trace, for capacity planning and for replaying against other
+// cache replacement policies with the recache-sim command.
+//
+// Traces are written as JSON lines, one [Record] per line. By default, records
+// identify patterns by their cache key only; use [WithPatterns] to record the
+// patterns themselves. Use [WithSampleRate] to record only a fraction of the
+// patterns, and [NewFile] to rotate trace files by size.
+//
+// Whether a lookup hit the cache is reported by the wrapped cache through
+// [recache.Observe]; every cache in this module does so.
+//
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+// [recache.Observe]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Observe
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"regexp"
+	"sync"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithSampleRate records only the given fraction of patterns, between 0 and 1.
+//
+// Patterns are sampled by key rather than by lookup, so every lookup of a
+// sampled pattern is recorded and the trace keeps its reuse pattern. A trace
+// sampled at rate R replayed against a cache of capacity C approximates the
+// full workload against a cache of capacity C/R.
+func WithSampleRate(rate float64) Option {
+	return func(r *Recorder) {
+		switch {
+		case rate >= 1 || math.IsNaN(rate):
+			r.threshold = math.MaxUint64
+		case rate <= 0:
+			r.threshold = 0
+		default:
+			// Scale by 2^63 and shift, as rates close to 1 would round to
+			// 2^64 and overflow.
+			r.threshold = uint64(rate*(1<<63)) << 1
+		}
+	}
+}
+
+// WithPatterns records the patterns themselves, rather than only their keys.
+// Patterns may contain sensitive data, so only enable this if the traces are
+// handled accordingly.
+func WithPatterns() Option {
+	return func(r *Recorder) {
+		r.patterns = true
+	}
+}
+
+// Recorder is a recache.Cache that records every lookup made through it before
+// delegating to another cache.
+type Recorder struct {
+	// cache is the wrapped cache.
+	cache recache.Cache
+
+	// w receives the trace, one Write call per record.
+	w io.Writer
+
+	// err is the first error returned by w.
+	err error
+
+	// buf is reused to encode records.
+	buf []byte
+
+	// threshold is the largest key hash that is sampled.
+	threshold uint64
+
+	// patterns reports whether patterns are recorded.
+	patterns bool
+
+	// closed reports whether Close was called.
+	closed bool
+
+	// mu is a mutex that protects access to w, err, buf, and closed.
+	mu sync.Mutex
+}
+
+// Compile-time check to ensure Recorder implements the recache.Cache
+// interface.
+var _ recache.Cache = (*Recorder)(nil)
+
+// New returns a Recorder that wraps the given cache and writes its trace to w.
+// Each record is passed to w in a single Write call.
+func New(cache recache.Cache, w io.Writer, opts ...Option) *Recorder {
+	r := &Recorder{
+		cache:     cache,
+		w:         w,
+		threshold: math.MaxUint64,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Get returns a compiled regular expression from the wrapped cache, recording
+// the lookup if its pattern is sampled.
+func (r *Recorder) Get(ctx context.Context, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
+	key := recache.Key(pattern, flag)
+
+	if !r.sampled(key) {
+		return r.cache.Get(ctx, pattern, flag) //nolint:wrapcheck // the decorator is transparent
+	}
+
+	var (
+		obs   recache.Observation
+		start = time.Now()
+	)
+
+	regex, err := r.cache.Get(recache.WithObservation(ctx, &obs), pattern, flag)
+
+	rec := Record{
+		Time:    start,
+		Key:     key,
+		Flag:    flag,
+		Compile: obs.Compile,
+		Latency: time.Since(start),
+	}
+
+	if r.patterns {
+		rec.Pattern = pattern
+	}
+
+	switch {
+	case err != nil:
+		rec.Outcome = OutcomeError
+	case !obs.Observed:
+		rec.Outcome = OutcomeUnknown
+	case obs.Hit:
+		rec.Outcome = OutcomeHit
+	default:
+		rec.Outcome = OutcomeMiss
+	}
+
+	// Pass the outcome on to any observer further up, such as another
+	// recorder.
+	if obs.Observed {
+		recache.Observe(ctx, obs.Hit, obs.Compile)
+	}
+
+	r.write(rec)
+
+	return regex, err //nolint:wrapcheck // the decorator is transparent
+}
+
+// SetCapacity sets the capacity of the wrapped cache.
+func (r *Recorder) SetCapacity(capacity int) error {
+	return r.cache.SetCapacity(capacity) //nolint:wrapcheck // the decorator is transparent
+}
+
+// Capacity returns the capacity of the wrapped cache.
+func (r *Recorder) Capacity() int {
+	return r.cache.Capacity()
+}
+
+// Size returns the size of the wrapped cache.
+func (r *Recorder) Size() int {
+	return r.cache.Size()
+}
+
+// Clear clears the wrapped cache.
+func (r *Recorder) Clear() {
+	r.cache.Clear()
+}
+
+// Unwrap returns the wrapped cache.
+func (r *Recorder) Unwrap() recache.Cache {
+	return r.cache
+}
+
+// Err returns the first error encountered while writing the trace. Once an
+// error occurs, the recorder stops writing.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Close closes the trace writer if it implements io.Closer, and returns the
+// first error encountered while writing the trace, if any. The wrapped cache
+// keeps working, but lookups are no longer recorded.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return r.err
+	}
+
+	r.closed = true
+
+	if closer, ok := r.w.(io.Closer); ok {
+		if err := closer.Close(); err != nil && r.err == nil {
+			r.err = fmt.Errorf("%w", err)
+		}
+	}
+
+	return r.err
+}
+
+// write appends the record to the trace.
+func (r *Recorder) write(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || r.err != nil {
+		return
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		r.err = fmt.Errorf("%w", err)
+
+		return
+	}
+
+	r.buf = append(append(r.buf[:0], data...), '\n')
+
+	if _, err := r.w.Write(r.buf); err != nil {
+		r.err = fmt.Errorf("%w", err)
+	}
+}
+
+// sampled reports whether lookups of the pattern with the given key are
+// recorded.
+func (r *Recorder) sampled(key string) bool {
+	if r.threshold == math.MaxUint64 {
+		return true
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+
+	return hash.Sum64() < r.threshold
+}
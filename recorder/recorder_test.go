@@ -0,0 +1,340 @@
+package recorder_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recorder"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Outcomes", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		rec := recorder.New(lrure.New(recache.DefaultCapacity), &buf)
+
+		for _, pattern := range []string{"a+", "a+", "("} {
+			rec.Get(ctx, pattern, recache.DefaultFlag) //nolint:errcheck // errors are recorded
+		}
+
+		records, err := recorder.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+
+		want := []recorder.Outcome{recorder.OutcomeMiss, recorder.OutcomeHit, recorder.OutcomeError}
+
+		if len(records) != len(want) {
+			t.Fatalf("len(records) = %d, want %d", len(records), len(want))
+		}
+
+		for i, r := range records {
+			if r.Outcome != want[i] {
+				t.Errorf("records[%d].Outcome = %s, want %s", i, r.Outcome, want[i])
+			}
+
+			if r.Pattern != "" {
+				t.Errorf("records[%d].Pattern = %q, want empty without WithPatterns", i, r.Pattern)
+			}
+
+			if r.Time.IsZero() || r.Latency <= 0 {
+				t.Errorf("records[%d] = %+v, want a time and a latency", i, r)
+			}
+		}
+
+		if records[0].Key != recache.Key("a+", recache.DefaultFlag) || records[0].Key != records[1].Key {
+			t.Errorf("records keys = %q and %q, want recache.Key()", records[0].Key, records[1].Key)
+		}
+
+		if records[0].Compile <= 0 || records[1].Compile != 0 {
+			t.Errorf("Compile = %v and %v, want positive on miss and zero on hit", records[0].Compile, records[1].Compile)
+		}
+	})
+
+	t.Run("Patterns", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		rec := recorder.New(lrure.New(recache.DefaultCapacity), &buf, recorder.WithPatterns())
+
+		if _, err := rec.Get(ctx, "b+", recache.FlagPOSIX); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		records, err := recorder.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+
+		if len(records) != 1 || records[0].Pattern != "b+" || records[0].Flag != recache.FlagPOSIX {
+			t.Errorf("records = %+v, want one POSIX record of %q", records, "b+")
+		}
+	})
+
+	t.Run("Unknown outcome", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		rec := recorder.New(silentCache{lrure.New(recache.DefaultCapacity)}, &buf)
+
+		if _, err := rec.Get(ctx, "c+", recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		records, err := recorder.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+
+		if len(records) != 1 || records[0].Outcome != recorder.OutcomeUnknown {
+			t.Errorf("records = %+v, want one unknown outcome", records)
+		}
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			inner, outer bytes.Buffer
+			cache        = lrure.New(recache.DefaultCapacity)
+			rec          = recorder.New(recorder.New(cache, &inner), &outer)
+		)
+
+		if _, err := rec.Get(ctx, "d+", recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		records, err := recorder.NewReader(&outer).ReadAll()
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+
+		if len(records) != 1 || records[0].Outcome != recorder.OutcomeMiss {
+			t.Errorf("outer records = %+v, want one miss", records)
+		}
+	})
+
+	t.Run("Sampling", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name     string
+			rate     float64
+			min, max int
+		}{
+			{name: "None", rate: 0, min: 0, max: 0},
+			{name: "Half", rate: 0.5, min: 30, max: 70},
+			{name: "All", rate: 1, min: 100, max: 100},
+		}
+
+		for _, tt := range tests {
+			tt := tt
+
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				var buf bytes.Buffer
+
+				rec := recorder.New(lrure.New(200), &buf, recorder.WithSampleRate(tt.rate))
+
+				// Look every pattern up twice, so sampled patterns must show
+				// up as a miss followed by a hit.
+				for i := 0; i < 200; i++ {
+					rec.Get(ctx, fmt.Sprintf("p%d", i%100), recache.DefaultFlag) //nolint:errcheck // patterns are valid
+				}
+
+				records, err := recorder.NewReader(&buf).ReadAll()
+				if err != nil {
+					t.Fatalf("ReadAll() error = %v", err)
+				}
+
+				if got := len(records) / 2; got < tt.min || got > tt.max {
+					t.Errorf("sampled %d patterns, want between %d and %d", got, tt.min, tt.max)
+				}
+
+				counts := make(map[string]int)
+
+				for _, r := range records {
+					counts[r.Key]++
+				}
+
+				for key, count := range counts {
+					if count != 2 {
+						t.Errorf("key %s recorded %d times, want 2", key, count)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+
+		rec := recorder.New(lrure.New(recache.DefaultCapacity), &buf)
+
+		if err := rec.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		if _, err := rec.Get(ctx, "e+", recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() after Close() error = %v", err)
+		}
+
+		if buf.Len() != 0 {
+			t.Errorf("trace after Close() = %q, want empty", buf.String())
+		}
+	})
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Round trip", func(t *testing.T) {
+		t.Parallel()
+
+		want := recorder.Record{
+			Time:    time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC),
+			Key:     recache.Key("a+", recache.FlagMustPOSIX),
+			Pattern: "a+",
+			Flag:    recache.FlagMustPOSIX,
+			Outcome: recorder.OutcomeMiss,
+			Compile: 3 * time.Microsecond,
+			Latency: 5 * time.Microsecond,
+		}
+
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+
+		var got recorder.Record
+
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+
+		if got != want {
+			t.Errorf("round trip = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("Hand-written", func(t *testing.T) {
+		t.Parallel()
+
+		var got recorder.Record
+
+		if err := json.Unmarshal([]byte(`{"pattern": "a+"}`), &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+
+		if got.Key != recache.Key("a+", recache.DefaultFlag) || got.Outcome != recorder.OutcomeUnknown {
+			t.Errorf("Unmarshal() = %+v, want the key of %q and an unknown outcome", got, "a+")
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, data := range []string{`{"flag": "Nope"}`, `{"outcome": "maybe"}`, `{`} {
+			var got recorder.Record
+
+			if err := json.Unmarshal([]byte(data), &got); err == nil {
+				t.Errorf("Unmarshal(%s) error = nil, want error", data)
+			}
+		}
+	})
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx  = context.Background()
+		path = filepath.Join(t.TempDir(), "trace.jsonl")
+	)
+
+	file, err := recorder.NewFile(path, 512, 2)
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+
+	rec := recorder.New(lrure.New(recache.DefaultCapacity), file, recorder.WithPatterns())
+
+	const lookups = 40
+
+	for i := 0; i < lookups; i++ {
+		rec.Get(ctx, fmt.Sprintf("p%d", i), recache.DefaultFlag) //nolint:errcheck // patterns are valid
+	}
+
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("Stat(%s) error = %v", p, err)
+		}
+
+		if info.Size() > 512 {
+			t.Errorf("size of %s = %d, want at most 512", p, info.Size())
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Errorf("%s.3 exists, want at most 2 backups", path)
+	}
+
+	reader, err := recorder.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer reader.Close()
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	if len(records) == 0 || len(records) >= lookups {
+		t.Fatalf("len(records) = %d, want some but not all of %d after rotation", len(records), lookups)
+	}
+
+	// The oldest records were dropped with the oldest backup, and the rest
+	// must come out in order.
+	first := lookups - len(records)
+
+	for i, r := range records {
+		if want := fmt.Sprintf("p%d", first+i); r.Pattern != want {
+			t.Fatalf("records[%d].Pattern = %q, want %q", i, r.Pattern, want)
+		}
+	}
+}
+
+// silentCache is a cache that does not report whether lookups hit it.
+type silentCache struct {
+	recache.Cache
+}
+
+func (c silentCache) Get(_ context.Context, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
+	return c.Cache.Get(context.Background(), pattern, flag) //nolint:wrapcheck // test helper
+}
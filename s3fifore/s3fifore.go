@@ -0,0 +1,274 @@
+// Package s3fifore implements a thread-safe cache for [Go's standard regex
+// package] that complies with the [recache.Cache] interface. It uses
+// [S3-FIFO] as its cache replacement policy.
+//
+// S3-FIFO keeps new patterns in a small FIFO queue and only moves them to the
+// main FIFO queue if they are looked up again before reaching its end, while
+// a ghost queue remembers the keys recently evicted from the small queue so
+// they go straight to the main queue if they come back. Patterns in the main
+// queue that were looked up get another trip through it instead of being
+// evicted.
+//
+// Lookups only increment the atomic frequency counter of the entry, so cache
+// hits are served under a read lock and do not contend with each other,
+// unlike with [lrure], which has to reorder its list on every hit.
+//
+// [Go's standard regex package]: https://godocs.io/regexp
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+// [S3-FIFO]: https://s3fifo.com
+// [lrure]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go/lrure
+package s3fifore
+
+import (
+	"container/list"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+const (
+	// _smallRatio is the size of the small queue as a fraction of the
+	// capacity of the cache, as recommended by the paper.
+	_smallRatio float64 = 0.1
+
+	// _maxFrequency is the frequency at which entries saturate, which bounds
+	// the number of extra trips through the main queue an entry can earn.
+	_maxFrequency uint64 = 3
+)
+
+// Cache is a thread-safe regex cache using the S3-FIFO policy.
+type Cache struct {
+	*recache.Store
+}
+
+// Compile-time check to ensure Cache implements the recache.Cache interface.
+var _ recache.Cache = (*Cache)(nil)
+
+// New returns a new S3-FIFO cache with the given capacity and options, such as
+// [recache.WithLogger].
+//
+// If capacity is less than 1, [recache.DefaultCapacity] is used instead.
+//
+// [recache.DefaultCapacity]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#DefaultCapacity
+func New(capacity int, opts ...recache.StoreOption) *Cache {
+	return &Cache{
+		Store: recache.NewStore(capacity, NewPolicy(), opts...),
+	}
+}
+
+// Policy is the S3-FIFO cache replacement policy.
+type Policy struct {
+	// small is the FIFO queue new entries are added to, newest first.
+	small *list.List
+
+	// main is the FIFO queue of entries looked up while in the small queue or
+	// brought back from the ghost queue, newest first.
+	main *list.List
+
+	// ghost is the FIFO queue of the keys recently evicted from the small
+	// queue, newest first.
+	ghost *list.List
+
+	// elements is a map of the keys of tracked entries to their elements in
+	// either the small or the main queue.
+	elements map[string]*list.Element
+
+	// ghosts is a map of the keys in the ghost queue to their elements.
+	ghosts map[string]*list.Element
+
+	// victim is the key last returned by Victim, which is moved to the ghost
+	// queue if it is removed from the small queue.
+	victim string
+
+	// smallSize is the number of entries the small queue holds before it is
+	// evicted from.
+	smallSize int
+
+	// ghostSize is the maximum number of keys the ghost queue holds.
+	ghostSize int
+}
+
+// Compile-time check to ensure Policy implements the recache.Policy and
+// recache.ConcurrentAccessor interfaces.
+var _ recache.ConcurrentAccessor = (*Policy)(nil)
+
+// NewPolicy returns a new S3-FIFO cache replacement policy.
+func NewPolicy() *Policy {
+	p := &Policy{
+		small:    list.New(),
+		main:     list.New(),
+		ghost:    list.New(),
+		elements: make(map[string]*list.Element),
+		ghosts:   make(map[string]*list.Element),
+	}
+
+	p.Resize(recache.DefaultCapacity)
+
+	return p
+}
+
+// Access is a no-op, as the entry keeps track of its own frequency.
+func (*Policy) Access(_ *recache.Entry) {}
+
+// ConcurrentAccess marks Access as safe for concurrent use, so the store
+// serves hits under a read lock.
+func (*Policy) ConcurrentAccess() {}
+
+// Insert adds the given entry to the main queue if its key is in the ghost
+// queue, and to the small queue otherwise.
+func (p *Policy) Insert(entry *recache.Entry) {
+	key := entry.Key()
+	n := &node{entry: entry, base: entry.Frequency()}
+
+	if ghost, ok := p.ghosts[key]; ok {
+		p.ghost.Remove(ghost)
+		delete(p.ghosts, key)
+
+		n.main = true
+		p.elements[key] = p.main.PushFront(n)
+
+		return
+	}
+
+	p.elements[key] = p.small.PushFront(n)
+}
+
+// Victim returns the key of the entry to evict next.
+//
+// While the small queue holds more than its share of the cache, its oldest
+// entry is either moved to the main queue, if it was looked up since it was
+// added, or chosen. Otherwise, the oldest entry of the main queue is either
+// given another trip through it, at the cost of one unit of frequency, or
+// chosen.
+func (p *Policy) Victim() (string, bool) {
+	for {
+		if p.small.Len() > p.smallSize || p.main.Len() == 0 {
+			elem := p.small.Back()
+			if elem == nil {
+				return "", false
+			}
+
+			n, ok := elem.Value.(*node)
+			if !ok {
+				return "", false
+			}
+
+			if n.frequency() == 0 {
+				p.victim = n.entry.Key()
+
+				return p.victim, true
+			}
+
+			p.small.Remove(elem)
+
+			n.main = true
+			n.base = n.entry.Frequency()
+			p.elements[n.entry.Key()] = p.main.PushFront(n)
+
+			continue
+		}
+
+		elem := p.main.Back()
+
+		n, ok := elem.Value.(*node)
+		if !ok {
+			return "", false
+		}
+
+		if n.frequency() == 0 {
+			p.victim = n.entry.Key()
+
+			return p.victim, true
+		}
+
+		n.decrement()
+		p.main.MoveToFront(elem)
+	}
+}
+
+// Remove stops tracking the entry with the given key. If the entry was in the
+// small queue and was the last victim, its key is remembered in the ghost
+// queue.
+func (p *Policy) Remove(key string) {
+	victim := p.victim
+	p.victim = ""
+
+	elem, ok := p.elements[key]
+	if !ok {
+		return
+	}
+
+	delete(p.elements, key)
+
+	if n, ok := elem.Value.(*node); ok && n.main {
+		p.main.Remove(elem)
+
+		return
+	}
+
+	p.small.Remove(elem)
+
+	if key == victim {
+		p.ghosts[key] = p.ghost.PushFront(key)
+		p.trimGhosts()
+	}
+}
+
+// Resize sizes the small and ghost queues for the new capacity of the cache.
+func (p *Policy) Resize(capacity int) {
+	p.smallSize = int(float64(capacity) * _smallRatio)
+	if p.smallSize < 1 {
+		p.smallSize = 1
+	}
+
+	p.ghostSize = capacity - p.smallSize
+	if p.ghostSize < 1 {
+		p.ghostSize = 1
+	}
+
+	p.trimGhosts()
+}
+
+// trimGhosts forgets the oldest keys in the ghost queue until it fits its
+// size.
+func (p *Policy) trimGhosts() {
+	for p.ghost.Len() > p.ghostSize {
+		elem := p.ghost.Back()
+
+		if key, ok := elem.Value.(string); ok {
+			delete(p.ghosts, key)
+		}
+
+		p.ghost.Remove(elem)
+	}
+}
+
+// node is the value of the elements of the small and main queues.
+type node struct {
+	// entry is the tracked entry.
+	entry *recache.Entry
+
+	// base is the value of the entry's frequency counter that counts as zero
+	// for the policy. The counter keeps growing, so the policy moves the base
+	// instead of resetting it.
+	base uint64
+
+	// main reports whether the node is in the main queue rather than the
+	// small one.
+	main bool
+}
+
+// frequency returns the number of lookups of the entry since its frequency was
+// last reset, saturating at _maxFrequency.
+func (n *node) frequency() uint64 {
+	frequency := n.entry.Frequency() - n.base
+	if frequency > _maxFrequency {
+		return _maxFrequency
+	}
+
+	return frequency
+}
+
+// decrement lowers the frequency of the entry by one.
+func (n *node) decrement() {
+	n.base = n.entry.Frequency() - (n.frequency() - 1)
+}
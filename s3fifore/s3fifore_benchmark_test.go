@@ -0,0 +1,89 @@
+package s3fifore_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/mockingjayre"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/s3fifore"
+)
+
+// benchmarkCaches returns a fresh instance of every cache compared by the
+// benchmarks.
+func benchmarkCaches(capacity int) []struct {
+	name  string
+	cache statsCache
+} {
+	return []struct {
+		name  string
+		cache statsCache
+	}{
+		{name: "s3fifore", cache: s3fifore.New(capacity)},
+		{name: "lrure", cache: lrure.New(capacity)},
+		{name: "mockingjayre", cache: mockingjayre.New(capacity)},
+	}
+}
+
+// BenchmarkParallelHits measures lookups that always hit the cache from many
+// goroutines at once, where the lock taken on hits matters most.
+func BenchmarkParallelHits(b *testing.B) {
+	var caches []recachetest.NamedCache
+
+	for _, bc := range benchmarkCaches(recache.DefaultCapacity) {
+		caches = append(caches, recachetest.NamedCache{Name: bc.name, Cache: bc.cache})
+	}
+
+	recachetest.BenchmarkParallelHits(b, caches...)
+}
+
+// BenchmarkHitRatio replays Zipf-distributed lookups, with periodic scans of
+// patterns that are only looked up once, and reports the hit ratio of each
+// cache.
+func BenchmarkHitRatio(b *testing.B) {
+	const (
+		capacity = 64
+		patterns = 1_000
+	)
+
+	ctx := context.Background()
+
+	for _, bc := range benchmarkCaches(capacity) {
+		bc := bc
+
+		b.Run(bc.name, func(b *testing.B) {
+			var (
+				rng     = rand.New(rand.NewSource(1)) //nolint:gosec // benchmarks need not be unpredictable
+				zipf    = rand.NewZipf(rng, 1.1, 1, patterns-1)
+				scanned int
+			)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				var pattern string
+
+				if (i/100)%5 == 4 {
+					pattern = fmt.Sprintf(`^scan%d$`, scanned)
+					scanned++
+				} else {
+					pattern = fmt.Sprintf(`^p%d$`, zipf.Uint64())
+				}
+
+				if _, err := bc.cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			stats := bc.cache.Stats()
+
+			if lookups := stats.Hits + stats.Misses; lookups > 0 {
+				b.ReportMetric(float64(stats.Hits)/float64(lookups)*100, "%hits")
+			}
+		})
+	}
+}
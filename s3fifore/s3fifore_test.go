@@ -0,0 +1,282 @@
+package s3fifore_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/s3fifore"
+)
+
+func newEntry(key string) *recache.Entry {
+	return recache.NewEntry(key, key, regexp.MustCompile(regexp.QuoteMeta(key)))
+}
+
+// evict asks the policy for a victim and removes it, as the store does.
+func evict(t *testing.T, p *s3fifore.Policy) string {
+	t.Helper()
+
+	key, ok := p.Victim()
+	if !ok {
+		t.Fatal("Victim() returned false, want a victim")
+	}
+
+	p.Remove(key)
+
+	return key
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		p := s3fifore.NewPolicy()
+
+		if _, ok := p.Victim(); ok {
+			t.Errorf("Victim() on an empty policy should return false")
+		}
+	})
+
+	t.Run("Small queue is FIFO", func(t *testing.T) {
+		t.Parallel()
+
+		p := s3fifore.NewPolicy()
+		p.Resize(10)
+
+		for _, key := range []string{"a", "b", "c"} {
+			p.Insert(newEntry(key))
+		}
+
+		if key := evict(t, p); key != "a" {
+			t.Errorf("Victim() = %q, want %q", key, "a")
+		}
+	})
+
+	t.Run("Looked up entries move to the main queue", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			p = s3fifore.NewPolicy()
+			a = newEntry("a")
+		)
+
+		p.Resize(10)
+
+		p.Insert(a)
+		p.Insert(newEntry("b"))
+		p.Insert(newEntry("c"))
+
+		if _, _, err := a.Load(); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		// "a" moves to the main queue and "b" is evicted. The small queue is
+		// then within its share of one entry, so the main queue is evicted
+		// from, and "a" was not looked up again since it moved.
+		for _, want := range []string{"b", "a", "c"} {
+			if key := evict(t, p); key != want {
+				t.Errorf("Victim() = %q, want %q", key, want)
+			}
+		}
+	})
+
+	t.Run("Ghost hit goes to the main queue", func(t *testing.T) {
+		t.Parallel()
+
+		p := s3fifore.NewPolicy()
+		p.Resize(10)
+
+		p.Insert(newEntry("a"))
+		p.Insert(newEntry("b"))
+
+		if key := evict(t, p); key != "a" {
+			t.Fatalf("Victim() = %q, want %q", key, "a")
+		}
+
+		p.Insert(newEntry("a"))
+		p.Insert(newEntry("c"))
+
+		// The small queue holds "b" and "c", more than its share of one entry,
+		// so it is evicted from first, and then "a" in the main queue.
+		for _, want := range []string{"b", "a", "c"} {
+			if key := evict(t, p); key != want {
+				t.Errorf("Victim() = %q, want %q", key, want)
+			}
+		}
+	})
+
+	t.Run("Main queue gives looked up entries another trip", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			p       = s3fifore.NewPolicy()
+			entries = make(map[string]*recache.Entry)
+		)
+
+		p.Resize(10)
+
+		// Evict every key to the ghost queue and bring them back, so they all
+		// end up in the main queue.
+		for _, key := range []string{"a", "b", "c"} {
+			p.Insert(newEntry(key))
+		}
+
+		for i := 0; i < 3; i++ {
+			evict(t, p)
+		}
+
+		for _, key := range []string{"a", "b", "c"} {
+			entries[key] = newEntry(key)
+			p.Insert(entries[key])
+		}
+
+		if _, _, err := entries["a"].Load(); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+
+		for _, want := range []string{"b", "c", "a"} {
+			if key := evict(t, p); key != want {
+				t.Errorf("Victim() = %q, want %q", key, want)
+			}
+		}
+	})
+}
+
+func TestCacheScanResistance(t *testing.T) {
+	t.Parallel()
+
+	const capacity = 20
+
+	// run looks up a hot set of patterns a few times, then scans many
+	// patterns once, and returns the number of hits on the hot set afterwards.
+	run := func(t *testing.T, cache statsCache) int {
+		t.Helper()
+
+		ctx := context.Background()
+
+		get := func(pattern string) {
+			t.Helper()
+
+			if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+				t.Fatalf("Get(%q) error = %v", pattern, err)
+			}
+		}
+
+		hot := []string{`^h0$`, `^h1$`, `^h2$`}
+
+		for round := 0; round < 3; round++ {
+			for _, pattern := range hot {
+				get(pattern)
+			}
+		}
+
+		for i := 0; i < capacity*10; i++ {
+			get(fmt.Sprintf(`^scan%d$`, i))
+		}
+
+		hits := 0
+
+		for _, pattern := range hot {
+			before := cache.Stats().Hits
+
+			get(pattern)
+
+			if cache.Stats().Hits > before {
+				hits++
+			}
+		}
+
+		return hits
+	}
+
+	if hits := run(t, s3fifore.New(capacity)); hits != 3 {
+		t.Errorf("S3-FIFO served %d of 3 hot patterns after a scan, want 3", hits)
+	}
+
+	if hits := run(t, lrure.New(capacity)); hits != 0 {
+		t.Errorf("LRU served %d of 3 hot patterns after a scan, want 0", hits)
+	}
+}
+
+func TestCacheConcurrentHits(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = s3fifore.New(8)
+		wg    sync.WaitGroup
+	)
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 200; i++ {
+				pattern := fmt.Sprintf(`^p%d$`, (g+i)%12)
+
+				regex, err := cache.Get(ctx, pattern, recache.DefaultFlag)
+				if err != nil || regex.String() != pattern {
+					t.Errorf("Get(%q) = %v, %v", pattern, regex, err)
+
+					return
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	if size := cache.Size(); size > 8 {
+		t.Errorf("Size() = %d, want at most 8", size)
+	}
+}
+
+func TestCachePin(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = s3fifore.New(2)
+	)
+
+	if err := cache.Pin(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := cache.Get(ctx, fmt.Sprintf(`^p%d`, i), recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	if _, err := cache.Get(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Errorf("Stats().Hits = %d, want 1 with the pinned pattern still cached", stats.Hits)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	recachetest.Run(t, func(capacity int) recache.Cache {
+		return s3fifore.New(capacity)
+	})
+}
+
+// statsCache is a cache that reports its activity.
+type statsCache interface {
+	recache.Cache
+	recache.StatsReporter
+}
@@ -0,0 +1,191 @@
+// Package sampledre implements a thread-safe cache for [Go's standard regex
+// package] that complies with the [recache.Cache] interface. It evicts like
+// [Redis] does: it samples a few random entries and evicts the worst of them,
+// either the least recently or the least frequently used.
+//
+// The policy keeps no list ordered by use, so hits only update the atomic
+// counters of the entry and are served under a read lock, and eviction costs
+// O(K) for K samples rather than a scan of the whole cache. The price is
+// that the evicted entry is only approximately the worst one, which gets more
+// precise as K grows.
+//
+// [Go's standard regex package]: https://godocs.io/regexp
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+// [Redis]: https://redis.io/docs/reference/eviction/#approximated-lru-algorithm
+package sampledre
+
+import (
+	"math/rand"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// DefaultSamples is the default number of entries sampled on eviction, which
+// is also the default used by Redis.
+const DefaultSamples int = 5
+
+// Mode decides which of the sampled entries is evicted.
+type Mode int
+
+const (
+	// ModeLRU evicts the least recently used of the sampled entries.
+	ModeLRU Mode = iota
+
+	// ModeLFU evicts the least frequently used of the sampled entries,
+	// breaking ties by evicting the least recently used.
+	ModeLFU
+)
+
+// String returns a string representation of the mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeLFU:
+		return "LFU"
+	default:
+		return "LRU"
+	}
+}
+
+// Cache is a thread-safe regex cache using sampled eviction.
+type Cache struct {
+	*recache.Store
+}
+
+// Compile-time check to ensure Cache implements the recache.Cache interface.
+var _ recache.Cache = (*Cache)(nil)
+
+// New returns a new cache with the given capacity and options, such as
+// [recache.WithLogger], which evicts the least recently used of
+// [DefaultSamples] random entries.
+//
+// If capacity is less than 1, [recache.DefaultCapacity] is used instead.
+//
+// [recache.DefaultCapacity]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#DefaultCapacity
+func New(capacity int, opts ...recache.StoreOption) *Cache {
+	return NewWithSampling(capacity, DefaultSamples, ModeLRU, opts...)
+}
+
+// NewWithSampling is like New, but samples the given number of entries on
+// eviction and picks the one to evict according to the given mode.
+func NewWithSampling(capacity, samples int, mode Mode, opts ...recache.StoreOption) *Cache {
+	return &Cache{
+		Store: recache.NewStore(capacity, NewPolicy(samples, mode), opts...),
+	}
+}
+
+// Policy is the sampled cache replacement policy.
+type Policy struct {
+	// indexes is a map of the keys of tracked entries to their index in
+	// entries.
+	indexes map[string]int
+
+	// entries holds the tracked entries in no particular order, so random
+	// ones can be picked in constant time.
+	entries []*recache.Entry
+
+	// samples is the number of entries sampled on eviction.
+	samples int
+
+	// mode decides which of the sampled entries is evicted.
+	mode Mode
+}
+
+// Compile-time check to ensure Policy implements the recache.Policy and
+// recache.ConcurrentAccessor interfaces.
+var _ recache.ConcurrentAccessor = (*Policy)(nil)
+
+// NewPolicy returns a new sampled cache replacement policy that samples the
+// given number of entries on eviction. If samples is less than 1,
+// DefaultSamples is used instead.
+func NewPolicy(samples int, mode Mode) *Policy {
+	if samples < 1 {
+		samples = DefaultSamples
+	}
+
+	return &Policy{
+		indexes: make(map[string]int),
+		samples: samples,
+		mode:    mode,
+	}
+}
+
+// Access is a no-op, as the entry keeps track of its own frequency and last
+// access.
+func (*Policy) Access(_ *recache.Entry) {}
+
+// ConcurrentAccess marks Access as safe for concurrent use, so the store
+// serves hits under a read lock.
+func (*Policy) ConcurrentAccess() {}
+
+// Insert starts tracking the given entry.
+func (p *Policy) Insert(entry *recache.Entry) {
+	if _, ok := p.indexes[entry.Key()]; ok {
+		return
+	}
+
+	p.indexes[entry.Key()] = len(p.entries)
+	p.entries = append(p.entries, entry)
+}
+
+// Victim returns the key of the worst of a random sample of entries. If the
+// policy tracks no more entries than it samples, all of them are considered.
+func (p *Policy) Victim() (string, bool) {
+	if len(p.entries) == 0 {
+		return "", false
+	}
+
+	var victim *recache.Entry
+
+	if len(p.entries) <= p.samples {
+		for _, entry := range p.entries {
+			if victim == nil || p.worse(entry, victim) {
+				victim = entry
+			}
+		}
+
+		return victim.Key(), true
+	}
+
+	for i := 0; i < p.samples; i++ {
+		entry := p.entries[rand.Intn(len(p.entries))] //nolint:gosec // eviction need not be unpredictable
+
+		if victim == nil || p.worse(entry, victim) {
+			victim = entry
+		}
+	}
+
+	return victim.Key(), true
+}
+
+// Remove stops tracking the entry with the given key.
+func (p *Policy) Remove(key string) {
+	i, ok := p.indexes[key]
+	if !ok {
+		return
+	}
+
+	last := len(p.entries) - 1
+
+	p.entries[i] = p.entries[last]
+	p.indexes[p.entries[i].Key()] = i
+
+	p.entries[last] = nil
+	p.entries = p.entries[:last]
+
+	delete(p.indexes, key)
+}
+
+// Resize is a no-op, as the sampled policy does not depend on the capacity of
+// the cache.
+func (*Policy) Resize(_ int) {}
+
+// worse reports whether a should be evicted before b.
+func (p *Policy) worse(a, b *recache.Entry) bool {
+	if p.mode == ModeLFU {
+		if fa, fb := a.Frequency(), b.Frequency(); fa != fb {
+			return fa < fb
+		}
+	}
+
+	return a.Accessed().Before(b.Accessed())
+}
@@ -0,0 +1,180 @@
+package sampledre_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/sampledre"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	// setup tracks entries "a", "b", and "c", created a minute apart, and
+	// then loads "a" once and "c" twice, a minute apart.
+	setup := func(p *sampledre.Policy) {
+		clock := recachetest.NewFakeClock(time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC))
+		entries := make(map[string]*recache.Entry)
+
+		for _, key := range []string{"a", "b", "c"} {
+			entries[key] = recache.NewEntryWithClock(key, key, recache.DefaultFlag, regexp.MustCompile(key), clock)
+			p.Insert(entries[key])
+
+			clock.Advance(time.Minute)
+		}
+
+		for _, key := range []string{"c", "c", "a"} {
+			if _, _, err := entries[key].Load(); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			clock.Advance(time.Minute)
+		}
+	}
+
+	tests := []struct {
+		name string
+		mode sampledre.Mode
+		want []string
+	}{
+		{
+			name: "LRU",
+			mode: sampledre.ModeLRU,
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "LFU",
+			mode: sampledre.ModeLFU,
+			want: []string{"b", "a", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Sampling at least as many entries as the policy tracks makes
+			// eviction exact.
+			p := sampledre.NewPolicy(3, tt.mode)
+
+			if _, ok := p.Victim(); ok {
+				t.Errorf("Victim() on an empty policy should return false")
+			}
+
+			setup(p)
+
+			for _, want := range tt.want {
+				key, ok := p.Victim()
+				if !ok {
+					t.Fatal("Victim() returned false, want a victim")
+				}
+
+				if key != want {
+					t.Errorf("Victim() = %q, want %q", key, want)
+				}
+
+				p.Remove(key)
+			}
+
+			if _, ok := p.Victim(); ok {
+				t.Errorf("Victim() after removing everything should return false")
+			}
+		})
+	}
+
+	t.Run("Sampled", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			p     = sampledre.NewPolicy(sampledre.DefaultSamples, sampledre.ModeLRU)
+			clock = recachetest.NewFakeClock(time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC))
+			ages  time.Duration
+		)
+
+		const size = 100
+
+		for i := 0; i < size; i++ {
+			key := fmt.Sprintf("p%d", i)
+			p.Insert(recache.NewEntryWithClock(key, key, recache.DefaultFlag, regexp.MustCompile(key), clock))
+
+			clock.Advance(time.Minute)
+		}
+
+		// Evicting half the entries, the victims must be older on average
+		// than the entries as a whole, which are 50 minutes old on average.
+		tracked := make(map[string]bool, size)
+
+		for i := 0; i < size; i++ {
+			tracked[fmt.Sprintf("p%d", i)] = true
+		}
+
+		for i := 0; i < size/2; i++ {
+			key, ok := p.Victim()
+			if !ok || !tracked[key] {
+				t.Fatalf("Victim() = %q, %t, want a tracked key", key, ok)
+			}
+
+			var n int
+
+			if _, err := fmt.Sscanf(key, "p%d", &n); err != nil {
+				t.Fatalf("Sscanf() error = %v", err)
+			}
+
+			ages += time.Duration(size-n) * time.Minute
+
+			delete(tracked, key)
+			p.Remove(key)
+		}
+
+		if average := ages / (size / 2); average < 60*time.Minute {
+			t.Errorf("average age of victims = %v, want at least 60m", average)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		clock = recachetest.NewFakeClock(time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC))
+		cache = sampledre.NewWithSampling(3, 3, sampledre.ModeLRU, recache.WithClock(clock))
+	)
+
+	for _, pattern := range []string{`^a`, `^b`, `^c`, `^a`, `^d`} {
+		if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+
+		clock.Advance(time.Minute)
+	}
+
+	for _, entry := range cache.Entries() {
+		if entry.Pattern() == `^b` {
+			t.Errorf("Entries() includes %q, want it evicted as the least recently used", entry.Pattern())
+		}
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []sampledre.Mode{sampledre.ModeLRU, sampledre.ModeLFU} {
+		mode := mode
+
+		t.Run(mode.String(), func(t *testing.T) {
+			t.Parallel()
+
+			recachetest.Run(t, func(capacity int) recache.Cache {
+				return sampledre.NewWithSampling(capacity, sampledre.DefaultSamples, mode)
+			})
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package recache
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// _setGroupPrefix prefixes the names of the groups wrapping each pattern in
+// the combined alternation of a Set.
+const _setGroupPrefix string = "recache_set_"
+
+// Set is a set of regular expressions that reports which of them match an
+// input, and is safe for concurrent use.
+//
+// Most patterns require some literal text in every match, so a set first
+// searches the input for the literals of all its patterns at once, and only
+// runs the patterns whose literals it found. Patterns without such literals
+// that can be combined are also compiled as one alternation of named groups,
+// so inputs that match none of them are rejected with a single scan. Inputs
+// that do match are then checked against the patterns the alternation did not
+// report, since an alternation only reports the first alternative that matches
+// at a given position. Patterns compiled with FlagPOSIX and patterns with
+// named groups of their own are never combined, and are always matched
+// individually.
+type Set struct {
+	// prefilter finds the patterns whose required literals appear in an
+	// input, or is nil if no pattern has any.
+	prefilter *prefilter
+
+	// combined is the alternation of the combinable patterns without required
+	// literals, or nil if there are none or the alternation failed to compile.
+	combined *regexp.Regexp
+
+	// groups maps the index of every named group in combined to the indices
+	// of the patterns it wraps. Duplicate patterns share a group.
+	groups map[int][]int
+
+	// members maps the index of every distinct pattern, sorted by cache key,
+	// to the indices the pattern was given at. The prefilter identifies
+	// patterns by the former.
+	members [][]int
+
+	// regexes holds the individually compiled patterns, in the order they
+	// were given.
+	regexes []*regexp.Regexp
+
+	// combinable reports, for every pattern, whether it is part of combined.
+	combinable []bool
+
+	// patterns holds the patterns, in the order they were given.
+	patterns []string
+}
+
+// NewSet compiles the given patterns with the given flag through the cache,
+// along with the alternation combining the patterns that can be combined, and
+// returns the resulting set. If cache is nil, the patterns are compiled
+// directly.
+//
+// The alternation lists the patterns in the order of their cache keys, so it
+// is cached under the same key whatever order the patterns are given in.
+// Likewise, the literal prefilter of the most recently built sets is cached by
+// the sorted keys of their patterns, so building the same set again only costs
+// cache lookups.
+func NewSet(ctx context.Context, cache Cache, flag Flag, patterns ...string) (*Set, error) {
+	s := &Set{
+		regexes:    make([]*regexp.Regexp, len(patterns)),
+		combinable: make([]bool, len(patterns)),
+		patterns:   append([]string(nil), patterns...),
+	}
+
+	// Map every distinct pattern to the indices it was given at.
+	indices := make(map[string][]int)
+
+	for i, pattern := range patterns {
+		regex, err := compileFor(ctx, cache, pattern, flag)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d: %w", i, err)
+		}
+
+		s.regexes[i] = regex
+		indices[pattern] = append(indices[pattern], i)
+	}
+
+	if len(indices) == 0 {
+		return s, nil
+	}
+
+	var (
+		sorted   = sortByKey(indices, flag)
+		keys     = make([]string, len(sorted))
+		distinct = make([]string, len(sorted))
+	)
+
+	s.members = make([][]int, len(sorted))
+
+	for i, m := range sorted {
+		keys[i] = m.key
+		distinct[i] = m.pattern
+		s.members[i] = indices[m.pattern]
+	}
+
+	s.prefilter = _prefilters.get(setKey(keys, flag), distinct, flag)
+
+	// Only the patterns the prefilter cannot rule out are worth combining.
+	combining := make(map[string][]int)
+
+	for i, m := range sorted {
+		if s.prefilter != nil && s.prefilter.filtered[i] {
+			continue
+		}
+
+		if combinable(s.regexes[s.members[i][0]], flag) {
+			combining[m.pattern] = s.members[i]
+		}
+	}
+
+	if len(combining) > 0 {
+		s.combine(ctx, cache, flag, combining)
+	}
+
+	return s, nil
+}
+
+// MatchString returns the indices of the patterns that match s, in increasing
+// order, or nil if none of them do.
+func (s *Set) MatchString(str string) []int {
+	return s.match(
+		func(p *prefilter) []bool { return candidates(p, str) },
+		func(regex *regexp.Regexp) []int { return regex.FindStringSubmatchIndex(str) },
+		func(regex *regexp.Regexp) bool { return regex.MatchString(str) },
+	)
+}
+
+// Match returns the indices of the patterns that match b, in increasing order,
+// or nil if none of them do.
+func (s *Set) Match(b []byte) []int {
+	return s.match(
+		func(p *prefilter) []bool { return candidates(p, b) },
+		func(regex *regexp.Regexp) []int { return regex.FindSubmatchIndex(b) },
+		func(regex *regexp.Regexp) bool { return regex.Match(b) },
+	)
+}
+
+// Len returns the number of patterns in the set.
+func (s *Set) Len() int {
+	return len(s.patterns)
+}
+
+// Patterns returns the patterns in the set, in the order they were given.
+func (s *Set) Patterns() []string {
+	return append([]string(nil), s.patterns...)
+}
+
+// combine compiles the alternation of the given distinct patterns, sorted by
+// cache key, and records which patterns each of its groups wraps. If the
+// alternation fails to compile, the set falls back to matching every pattern
+// individually.
+func (s *Set) combine(ctx context.Context, cache Cache, flag Flag, indices map[string][]int) {
+	members := sortByKey(indices, flag)
+
+	var b strings.Builder
+
+	for i, m := range members {
+		if i > 0 {
+			b.WriteByte('|')
+		}
+
+		b.WriteString("(?P<" + _setGroupPrefix + strconv.Itoa(i) + ">(?:" + m.pattern + "))")
+	}
+
+	combined, err := compileFor(ctx, cache, b.String(), DefaultFlag)
+	if err != nil {
+		return
+	}
+
+	groups := make(map[int][]int, len(members))
+
+	for i, m := range members {
+		group := combined.SubexpIndex(_setGroupPrefix + strconv.Itoa(i))
+
+		// A pattern that changes how the rest of the alternation parses
+		// should not compile, but never trust a group that went missing.
+		if group < 0 {
+			return
+		}
+
+		groups[group] = indices[m.pattern]
+	}
+
+	s.combined = combined
+	s.groups = groups
+
+	for _, is := range groups {
+		for _, i := range is {
+			s.combinable[i] = true
+		}
+	}
+}
+
+// match returns the indices of the matching patterns, using found to run the
+// prefilter, find to run the combined alternation, and matches to run
+// individual patterns.
+func (s *Set) match(found func(*prefilter) []bool, find func(*regexp.Regexp) []int, matches func(*regexp.Regexp) bool) []int {
+	var (
+		matched = make([]bool, len(s.regexes))
+		checked = make([]bool, len(s.regexes))
+	)
+
+	if s.prefilter != nil {
+		// Patterns whose required literals are not in the input cannot
+		// match.
+		for member, candidate := range found(s.prefilter) {
+			if candidate {
+				continue
+			}
+
+			for _, i := range s.members[member] {
+				checked[i] = true
+			}
+		}
+	}
+
+	if s.combined != nil {
+		loc := find(s.combined)
+
+		for i, combinable := range s.combinable {
+			// None of the combined patterns match if the alternation does
+			// not.
+			if combinable && loc == nil {
+				checked[i] = true
+			}
+		}
+
+		for group, indices := range s.groups {
+			if loc == nil || loc[2*group] < 0 {
+				continue
+			}
+
+			for _, i := range indices {
+				matched[i], checked[i] = true, true
+			}
+		}
+	}
+
+	var indices []int
+
+	for i, regex := range s.regexes {
+		if !checked[i] {
+			matched[i] = matches(regex)
+		}
+
+		if matched[i] {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+// setMember is a distinct pattern of a Set and its cache key.
+type setMember struct {
+	key     string
+	pattern string
+}
+
+// sortByKey returns the given distinct patterns sorted by their cache key for
+// the given flag.
+func sortByKey(indices map[string][]int, flag Flag) []setMember {
+	members := make([]setMember, 0, len(indices))
+
+	for pattern := range indices {
+		members = append(members, setMember{
+			key:     Key(pattern, flag),
+			pattern: pattern,
+		})
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].key < members[j].key
+	})
+
+	return members
+}
+
+// combinable reports whether the given compiled pattern can be part of the
+// alternation of a Set. POSIX patterns use different matching rules, and named
+// groups could clash with those of other patterns.
+func combinable(regex *regexp.Regexp, flag Flag) bool {
+	if flag == FlagPOSIX || flag == FlagMustPOSIX {
+		return false
+	}
+
+	for _, name := range regex.SubexpNames() {
+		if name != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compileFor compiles the given pattern through the cache, or directly if
+// cache is nil.
+func compileFor(ctx context.Context, cache Cache, pattern string, flag Flag) (*regexp.Regexp, error) {
+	if cache == nil {
+		regex, err := Compile(pattern, flag)
+		if err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+
+		return regex, nil
+	}
+
+	regex, err := cache.Get(ctx, pattern, flag)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return regex, nil
+}
@@ -0,0 +1,317 @@
+package recache_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+)
+
+func TestSet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		give      []string
+		giveFlag  recache.Flag
+		giveInput string
+		want      []int
+	}{
+		{
+			name:      "No match",
+			give:      []string{`^foo`, `bar$`, `[0-9]+`},
+			giveInput: "hello",
+		},
+		{
+			name:      "Every matching pattern",
+			give:      []string{`^foo`, `o+`, `[0-9]+`, `oo b`},
+			giveInput: "foo bar",
+			want:      []int{0, 1, 3},
+		},
+		{
+			name:      "Alternations inside patterns",
+			give:      []string{`a|b`, `^(c|d)$`, `e`},
+			giveInput: "d",
+			want:      []int{1},
+		},
+		{
+			name:      "Flags inside patterns",
+			give:      []string{`(?i)HELLO`, `hello`, `(?s)a.b`},
+			giveInput: "Hello a\nb",
+			want:      []int{0, 2},
+		},
+		{
+			name:      "Duplicate patterns",
+			give:      []string{`ab`, `x`, `ab`},
+			giveInput: "cab",
+			want:      []int{0, 2},
+		},
+		{
+			name:      "Named groups are matched individually",
+			give:      []string{`(?P<word>[a-z]+)`, `(?P<word>[0-9]+)`, `z`},
+			giveInput: "abc 123",
+			want:      []int{0, 1},
+		},
+		{
+			name:      "POSIX",
+			give:      []string{`^a+`, `b+$`, `c`},
+			giveFlag:  recache.FlagPOSIX,
+			giveInput: "aab",
+			want:      []int{0, 1},
+		},
+		{
+			name:      "Empty set",
+			giveInput: "anything",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			set, err := recache.NewSet(ctx, lrure.New(recache.DefaultCapacity), tt.giveFlag, tt.give...)
+			if err != nil {
+				t.Fatalf("NewSet() error = %v", err)
+			}
+
+			if got := set.MatchString(tt.giveInput); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("MatchString(%q) = %v, want %v", tt.giveInput, got, tt.want)
+			}
+
+			if got := set.Match([]byte(tt.giveInput)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Match(%q) = %v, want %v", tt.giveInput, got, tt.want)
+			}
+
+			if set.Len() != len(tt.give) {
+				t.Errorf("Len() = %d, want %d", set.Len(), len(tt.give))
+			}
+		})
+	}
+}
+
+func TestSetMatchesIndividualPatterns(t *testing.T) {
+	t.Parallel()
+
+	var (
+		patterns = []string{`^a`, `a$`, `ab+`, `b{2}`, `[ac]c`, `^$`, `\bba`, `(a|b)c`, `(?i)A`}
+		inputs   = []string{"", "a", "b", "ab", "abb", "ba", "cc", "bc", "aca", "x ba", "bbb", "A"}
+	)
+
+	set, err := recache.NewSet(context.Background(), nil, recache.DefaultFlag, patterns...)
+	if err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+
+	for _, input := range inputs {
+		var want []int
+
+		for i, pattern := range patterns {
+			if regexp.MustCompile(pattern).MatchString(input) {
+				want = append(want, i)
+			}
+		}
+
+		if got := set.MatchString(input); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("MatchString(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestSetPrefilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		give     []string
+		giveFlag recache.Flag
+		inputs   []string
+	}{
+		{
+			name: "Literals",
+			give: []string{`error: .*`, `^GET /admin`, `timeout after [0-9]+s`, `(foo|bar)baz`, `x{2,}y`, `[0-9]+`, `a?b`},
+			inputs: []string{
+				"", "error: disk full", "GET /admin/users", "POST /admin", "timeout after 30s",
+				"timeout after s", "foobaz", "barbaz", "bazbar", "xxy", "xy", "42", "b", "ERROR: disk",
+			},
+		},
+		{
+			name:   "Overlapping literals",
+			give:   []string{`he`, `she`, `his`, `hers`, `rs$`},
+			inputs: []string{"ushers", "ahishe", "hhhers", "sh", "hes"},
+		},
+		{
+			name:   "Case-insensitive literals",
+			give:   []string{`(?i)error`, `(?i)kelvin`, `(?i)class`, `(?i)straße`, `Error`},
+			inputs: []string{"ERROR", "Error", "error", "\u212Aelvin", "KELVIN", "cla\u017F\u017F", "STRASSE", "straße", "STRAßE"},
+		},
+		{
+			name:   "Unicode literals",
+			give:   []string{`café`, `日本`, `(?i)éclair`},
+			inputs: []string{"un café", "CAFÉ", "日本語", "Éclair", "éclair", "\xffcaf\xc3\xa9"},
+		},
+		{
+			name:   "Invalid UTF-8",
+			give:   []string{`a\x{FFFD}b`, `(?i)x\x{FFFD}Y`, `\x{FFFD}`, `caf\x{FFFD}`},
+			inputs: []string{"a\xffb", "a\ufffdb", "ab", "X\xc3Y", "x\xc3\xa9y", "\xff", "caf\xc3"},
+		},
+		{
+			name:     "POSIX",
+			give:     []string{`^abc`, `def$`, `x|yz`},
+			giveFlag: recache.FlagPOSIX,
+			inputs:   []string{"abcdef", "zdef", "y", "yz", "ab"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			set, err := recache.NewSet(context.Background(), nil, tt.giveFlag, tt.give...)
+			if err != nil {
+				t.Fatalf("NewSet() error = %v", err)
+			}
+
+			for _, input := range tt.inputs {
+				var want []int
+
+				for i, pattern := range tt.give {
+					regex, err := recache.Compile(pattern, tt.giveFlag)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if regex.MatchString(input) {
+						want = append(want, i)
+					}
+				}
+
+				if got := set.MatchString(input); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("MatchString(%q) = %v, want %v", input, got, want)
+				}
+
+				if got := set.Match([]byte(input)); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("Match(%q) = %v, want %v", input, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetMatchesRegexpOnRandomInputs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		patterns = []string{
+			`ab`, `(?i)AB`, `a\x{FFFD}b`, `\x{FFFD}\x{FFFD}`, `(?i)k`, `é+`, `(ab|ba)c`,
+			`^b`, `a$`, `[^a]b`, `(?i)\x{FFFD}é`, `x{2}`,
+		}
+		// The alphabet mixes ASCII, multibyte runes, U+FFFD itself, and bytes
+		// that are not valid UTF-8 on their own.
+		alphabet = []string{"a", "b", "c", "A", "B", "K", "x", "é", "\u212A", "\ufffd", "\xff", "\xc3", "\xa9", "\x80"}
+		random   = rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test inputs
+	)
+
+	set, err := recache.NewSet(context.Background(), nil, recache.DefaultFlag, patterns...)
+	if err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+
+	regexes := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		regexes[i] = regexp.MustCompile(pattern)
+	}
+
+	for n := 0; n < 5000; n++ {
+		var b strings.Builder
+
+		for length := random.Intn(8); length > 0; length-- {
+			b.WriteString(alphabet[random.Intn(len(alphabet))])
+		}
+
+		input := b.String()
+
+		var want []int
+
+		for i, regex := range regexes {
+			if regex.MatchString(input) {
+				want = append(want, i)
+			}
+		}
+
+		if got := set.MatchString(input); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("MatchString(%q) = %v, want %v", input, got, want)
+		}
+
+		if got := set.Match([]byte(input)); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Match(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestSetCache(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = lrure.New(recache.DefaultCapacity)
+	)
+
+	// Patterns without required literals, so they are combined.
+	if _, err := recache.NewSet(ctx, cache, recache.DefaultFlag, `[ab]+`, `[0-9]`, `\s`); err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+
+	// Three patterns and the alternation combining them.
+	if got := cache.Stats().Misses; got != 4 {
+		t.Fatalf("Stats().Misses after the first NewSet() = %d, want 4", got)
+	}
+
+	if _, err := recache.NewSet(ctx, cache, recache.DefaultFlag, `\s`, `[ab]+`, `[0-9]`); err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+
+	if got := cache.Stats().Misses; got != 4 {
+		t.Errorf("Stats().Misses after building the same set in another order = %d, want 4", got)
+	}
+
+	if _, err := recache.NewSet(ctx, cache, recache.DefaultFlag, `a+`, `(`); err == nil {
+		t.Errorf("NewSet() with an invalid pattern should return an error")
+	}
+}
+
+func BenchmarkSetMatchString(b *testing.B) {
+	const rules = 5_000
+
+	patterns := make([]string, rules)
+
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf(`rule%d: [a-z]+ id=[0-9]+`, i)
+	}
+
+	set, err := recache.NewSet(context.Background(), nil, recache.DefaultFlag, patterns...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	line := "2023-04-01T12:00:00Z rule4321: denied id=42 from 10.0.0.1"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if got := set.MatchString(line); len(got) != 1 {
+			b.Fatalf("MatchString() = %v, want one match", got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package recache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// _latencyBuckets are the upper bounds of the buckets used by Counters to
+// build the compile latency histogram.
+var _latencyBuckets = [...]time.Duration{
+	10 * time.Microsecond,
+	25 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+}
+
+// StatsReporter is implemented by caches that keep track of their activity.
+type StatsReporter interface {
+	// Stats returns a snapshot of the cache's counters.
+	Stats() Stats
+}
+
+// Stats is a snapshot of the activity of a cache.
+type Stats struct {
+	// CompileLatency is the distribution of the time spent compiling regular
+	// expressions, including the ones that failed to compile.
+	CompileLatency Histogram
+
+	// Hits is the number of times a regular expression was found in the
+	// cache.
+	Hits uint64
+
+	// Misses is the number of times a regular expression was not found in the
+	// cache and had to be compiled.
+	Misses uint64
+
+	// Evictions is the number of entries removed from the cache to make room
+	// for new ones.
+	Evictions uint64
+
+	// CompileErrors is the number of patterns that failed to compile.
+	CompileErrors uint64
+}
+
+// Histogram is a snapshot of a distribution of durations.
+type Histogram struct {
+	// Bounds are the inclusive upper bounds of each bucket, in increasing
+	// order.
+	Bounds []time.Duration
+
+	// Counts holds the number of observations in each bucket, which are not
+	// cumulative. It has one more element than Bounds, counting the
+	// observations greater than the last bound.
+	Counts []uint64
+
+	// Sum is the total of all observations.
+	Sum time.Duration
+
+	// Count is the number of observations.
+	Count uint64
+}
+
+// Counters is a set of thread-safe counters that caches can use to implement
+// the StatsReporter interface. The zero value is ready to use.
+//
+// Store and frozen caches keep their stats with it, and other
+// implementations of the Cache interface can use it to keep theirs.
+type Counters struct {
+	latency       [len(_latencyBuckets) + 1]atomic.Uint64
+	latencySum    atomic.Int64
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	evictions     atomic.Uint64
+	compileErrors atomic.Uint64
+}
+
+// Hit records a cache hit.
+func (c *Counters) Hit() {
+	c.hits.Add(1)
+}
+
+// Miss records a cache miss.
+func (c *Counters) Miss() {
+	c.misses.Add(1)
+}
+
+// Evict records the eviction of n entries.
+func (c *Counters) Evict(n int) {
+	c.evictions.Add(uint64(n))
+}
+
+// Compiled records that compiling a pattern took d, and whether it failed.
+func (c *Counters) Compiled(d time.Duration, err error) {
+	if err != nil {
+		c.compileErrors.Add(1)
+	}
+
+	bucket := len(_latencyBuckets)
+
+	for i, bound := range _latencyBuckets {
+		if d <= bound {
+			bucket = i
+
+			break
+		}
+	}
+
+	c.latency[bucket].Add(1)
+	c.latencySum.Add(int64(d))
+}
+
+// Stats returns a snapshot of the counters.
+func (c *Counters) Stats() Stats {
+	histogram := Histogram{
+		Bounds: append([]time.Duration(nil), _latencyBuckets[:]...),
+		Counts: make([]uint64, len(_latencyBuckets)+1),
+		Sum:    time.Duration(c.latencySum.Load()),
+	}
+
+	for i := range histogram.Counts {
+		histogram.Counts[i] = c.latency[i].Load()
+		histogram.Count += histogram.Counts[i]
+	}
+
+	return Stats{
+		CompileLatency: histogram,
+		Hits:           c.hits.Load(),
+		Misses:         c.misses.Load(),
+		Evictions:      c.evictions.Load(),
+		CompileErrors:  c.compileErrors.Load(),
+	}
+}
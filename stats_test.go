@@ -0,0 +1,42 @@
+package recache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+func TestCounters(t *testing.T) {
+	t.Parallel()
+
+	var counters recache.Counters
+
+	counters.Hit()
+	counters.Hit()
+	counters.Miss()
+	counters.Evict(3)
+	counters.Compiled(5*time.Microsecond, nil)
+	counters.Compiled(time.Second, errors.New("invalid pattern"))
+
+	stats := counters.Stats()
+
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 3 || stats.CompileErrors != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits, 1 miss, 3 evictions, and 1 compile error", stats)
+	}
+
+	histogram := stats.CompileLatency
+
+	if len(histogram.Counts) != len(histogram.Bounds)+1 {
+		t.Fatalf("len(Counts) = %d, want %d", len(histogram.Counts), len(histogram.Bounds)+1)
+	}
+
+	if histogram.Counts[0] != 1 || histogram.Counts[len(histogram.Counts)-1] != 1 {
+		t.Errorf("Counts = %v, want one observation in the first and last buckets", histogram.Counts)
+	}
+
+	if histogram.Count != 2 || histogram.Sum != time.Second+5*time.Microsecond {
+		t.Errorf("Count = %d, Sum = %v, want 2 and %v", histogram.Count, histogram.Sum, time.Second+5*time.Microsecond)
+	}
+}
@@ -0,0 +1,487 @@
+package recache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Store is a thread-safe regex cache that uses a [Policy] to decide which
+// entries to evict.
+//
+// It handles the storage, locking, compilation, and capacity plumbing shared by
+// every cache, so implementing a new cache replacement policy only requires
+// implementing the Policy interface. The default cache is a Store with the
+// [LRUPolicy], and most caches in this module are built on it too.
+type Store struct {
+	// entries is a map of the cache's keys to their entries.
+	entries map[string]*Entry
+
+	// pinned holds the keys of the entries that are never evicted. Pinned
+	// entries are not tracked by the policy, so it never picks them.
+	pinned map[string]struct{}
+
+	// policy decides which entry to evict when the cache is full.
+	policy Policy
+
+	// logger receives structured records of the cache's events, if set.
+	logger *slog.Logger
+
+	// clock is the clock the timestamps of entries are read from.
+	clock Clock
+
+	// counters keeps track of the cache's activity.
+	counters Counters
+
+	// capacity is the maximum number of items the cache can hold.
+	capacity int
+
+	// slowCompile is the duration after which a compile is logged as slow.
+	slowCompile time.Duration
+
+	// patternLogging controls how patterns are included in log records.
+	patternLogging PatternLogging
+
+	// concurrentAccess reports whether the policy implements
+	// ConcurrentAccessor, so hits only need a read lock.
+	concurrentAccess bool
+
+	// mu is a mutex that protects access to the cache and its policy.
+	mu sync.RWMutex
+}
+
+// Compile-time check to ensure Store implements the Cache, StatsReporter,
+// EntryLister, Deleter, and Pinner interfaces.
+var (
+	_ Cache         = (*Store)(nil)
+	_ StatsReporter = (*Store)(nil)
+	_ EntryLister   = (*Store)(nil)
+	_ Deleter       = (*Store)(nil)
+	_ Pinner        = (*Store)(nil)
+)
+
+// NewStore returns a new Store with the given capacity, cache replacement
+// policy, and options.
+//
+// If capacity is less than 1, [DefaultCapacity] is used instead.
+func NewStore(capacity int, policy Policy, opts ...StoreOption) *Store {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+
+	policy.Resize(capacity)
+
+	s := &Store{
+		entries:     make(map[string]*Entry, capacity),
+		pinned:      make(map[string]struct{}),
+		policy:      policy,
+		clock:       SystemClock(),
+		capacity:    capacity,
+		slowCompile: DefaultSlowCompile,
+	}
+
+	_, s.concurrentAccess = policy.(ConcurrentAccessor)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Get returns a compiled regular expression from the cache given a pattern and
+// an optional flag.
+//
+// If the regular expression is not in the cache, it is compiled outside the
+// lock and added to it.
+func (s *Store) Get(ctx context.Context, pattern string, flag Flag) (*regexp.Regexp, error) {
+	return s.GetWithKey(ctx, Key(pattern, flag), pattern, flag)
+}
+
+// GetWithKey is like Get, but stores the regular expression under the given
+// key instead of the one generated by [Key], which lets caches built
+// on a Store keep several namespaces of patterns apart.
+func (s *Store) GetWithKey(ctx context.Context, key, pattern string, flag Flag) (*regexp.Regexp, error) {
+	if regex, ok := s.load(key); ok {
+		s.counters.Hit()
+		Observe(ctx, true, 0)
+
+		return regex, nil
+	}
+
+	s.counters.Miss()
+
+	regex, err := s.compile(ctx, key, pattern, flag)
+	if err != nil {
+		return nil, err
+	}
+
+	regex, evicted, err := s.insert(key, pattern, flag, regex)
+
+	s.logEvictions(ctx, evicted)
+
+	return regex, err
+}
+
+// SetCapacity sets the maximum number of regular expressions that can be
+// stored in the cache, evicting entries if the cache holds more than that.
+//
+// It returns [ErrPinLimit] if the cache holds more pinned regular
+// expressions than the new capacity.
+func (s *Store) SetCapacity(capacity int) error {
+	if capacity < 1 {
+		return fmt.Errorf("%w", ErrInvalidCapacity)
+	}
+
+	s.mu.Lock()
+
+	if len(s.pinned) > capacity {
+		s.mu.Unlock()
+
+		return fmt.Errorf("%w", ErrPinLimit)
+	}
+
+	previous := s.capacity
+
+	s.capacity = capacity
+	s.policy.Resize(capacity)
+
+	evicted := s.evict(capacity)
+
+	s.mu.Unlock()
+
+	s.logCapacity(previous, capacity, len(evicted))
+	s.logEvictions(context.Background(), evicted)
+
+	return nil
+}
+
+// Capacity returns the maximum number of regular expressions that can be
+// stored in the cache.
+func (s *Store) Capacity() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.capacity
+}
+
+// Size returns the number of regular expressions currently stored in the
+// cache.
+func (s *Store) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.entries)
+}
+
+// Entries returns a snapshot of the entries currently stored in the cache, in
+// no particular order.
+func (s *Store) Entries() []*Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]*Entry, 0, len(s.entries))
+
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// Delete removes the regular expression compiled from the given pattern and
+// flag from the cache, and reports whether it was present.
+func (s *Store) Delete(pattern string, flag Flag) bool {
+	key := Key(pattern, flag)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; !ok {
+		return false
+	}
+
+	if _, ok := s.pinned[key]; ok {
+		delete(s.pinned, key)
+	} else {
+		s.policy.Remove(key)
+	}
+
+	delete(s.entries, key)
+
+	return true
+}
+
+// Pin compiles the given pattern with the given flag if needed and keeps it in
+// the cache until Unpin is called, evicting other entries to make room if
+// needed.
+//
+// Pinned regular expressions count against the cache's capacity, and Pin
+// returns [ErrPinLimit] if pinning one more would exceed it.
+func (s *Store) Pin(ctx context.Context, pattern string, flag Flag) error {
+	key := Key(pattern, flag)
+
+	for {
+		var regex *regexp.Regexp
+
+		if !s.contains(key) {
+			var err error
+
+			regex, err = s.compile(ctx, key, pattern, flag)
+			if err != nil {
+				return err
+			}
+		}
+
+		evicted, done, err := s.pin(key, pattern, flag, regex)
+		if err != nil {
+			return err
+		}
+
+		// The entry was evicted between the two locks and must be compiled
+		// again.
+		if !done {
+			continue
+		}
+
+		s.logEvictions(ctx, evicted)
+
+		return nil
+	}
+}
+
+// Unpin makes a pinned regular expression evictable again, and reports whether
+// it was pinned.
+func (s *Store) Unpin(pattern string, flag Flag) bool {
+	key := Key(pattern, flag)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pinned[key]; !ok {
+		return false
+	}
+
+	delete(s.pinned, key)
+	s.policy.Insert(s.entries[key])
+
+	return true
+}
+
+// Stats returns a snapshot of the cache's activity.
+func (s *Store) Stats() Stats {
+	return s.counters.Stats()
+}
+
+// Clear removes all regular expressions from the cache, including pinned
+// ones.
+func (s *Store) Clear() {
+	s.mu.Lock()
+
+	size := len(s.entries)
+
+	for key := range s.entries {
+		if _, ok := s.pinned[key]; !ok {
+			s.policy.Remove(key)
+		}
+	}
+
+	s.entries = make(map[string]*Entry, s.capacity)
+	s.pinned = make(map[string]struct{})
+
+	s.mu.Unlock()
+
+	s.logClear(size)
+}
+
+// Freeze returns an immutable view of the regular expressions currently stored
+// in the cache, including pinned ones, whose lookups take no lock. It is meant
+// to be called once the cache is warmed up, to serve latency-critical paths.
+//
+// The store keeps working as before, and later changes to it are not seen by
+// the frozen view. The frozen view holds copies of the store's entries, so its
+// lookups do not count as accesses when the store picks what to evict.
+func (s *Store) Freeze(opts ...FreezeOption) *Frozen {
+	s.mu.RLock()
+
+	var (
+		entries  = make(map[string]*Entry, len(s.entries))
+		capacity = s.capacity
+	)
+
+	for key, entry := range s.entries {
+		entries[key] = entry.Clone()
+	}
+
+	s.mu.RUnlock()
+
+	f := &Frozen{
+		capacity: capacity,
+	}
+
+	f.entries.Store(&entries)
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// load returns the regular expression stored under the given key, recording
+// the access with the policy. It only takes a read lock if the policy's Access
+// method is safe for concurrent use.
+func (s *Store) load(key string) (*regexp.Regexp, bool) {
+	if s.concurrentAccess {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+	} else {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if _, ok = s.pinned[key]; !ok {
+		s.policy.Access(entry)
+	}
+
+	regex, _, err := entry.Load()
+	if err != nil {
+		return nil, false
+	}
+
+	return regex, true
+}
+
+// contains reports whether the cache holds an entry for the given key.
+func (s *Store) contains(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.entries[key]
+
+	return ok
+}
+
+// compile compiles the given pattern, recording and logging how long it took.
+func (s *Store) compile(ctx context.Context, key, pattern string, flag Flag) (*regexp.Regexp, error) {
+	start := time.Now()
+	regex, err := Compile(pattern, flag)
+	duration := time.Since(start)
+
+	s.counters.Compiled(duration, err)
+	s.logCompile(ctx, key, pattern, flag, duration, err)
+	Observe(ctx, false, duration)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return regex, nil
+}
+
+// pin marks the entry with the given key as pinned, adding it to the cache
+// with the given regular expression if needed. It reports false if the entry
+// is not in the cache and regex is nil.
+func (s *Store) pin(key, pattern string, flag Flag, regex *regexp.Regexp) ([]*Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pinned[key]; ok {
+		return nil, true, nil
+	}
+
+	if len(s.pinned) >= s.capacity {
+		return nil, false, fmt.Errorf("%w", ErrPinLimit)
+	}
+
+	var evicted []*Entry
+
+	if _, ok := s.entries[key]; ok {
+		s.policy.Remove(key)
+	} else {
+		if regex == nil {
+			return nil, false, nil
+		}
+
+		evicted = s.evict(s.capacity - 1)
+
+		s.entries[key] = NewEntryWithClock(key, pattern, flag, regex, s.clock)
+	}
+
+	s.pinned[key] = struct{}{}
+
+	return evicted, true, nil
+}
+
+// insert adds a freshly compiled regular expression to the cache, returning
+// the regular expression to hand out and the entries evicted to make room for
+// it.
+func (s *Store) insert(key, pattern string, flag Flag, regex *regexp.Regexp) (*regexp.Regexp, []*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another goroutine may have added the same pattern while it was being
+	// compiled.
+	if entry, ok := s.entries[key]; ok {
+		if _, ok = s.pinned[key]; !ok {
+			s.policy.Access(entry)
+		}
+
+		regex, _, err := entry.Load()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w", err)
+		}
+
+		return regex, nil, nil
+	}
+
+	// Make room before inserting, so policies that favor older entries do not
+	// pick the new entry as the victim.
+	evicted := s.evict(s.capacity - 1)
+
+	// If pinned entries take up the whole capacity, the regular expression is
+	// handed out without being cached.
+	if len(s.entries) >= s.capacity {
+		return regex, evicted, nil
+	}
+
+	entry := NewEntryWithClock(key, pattern, flag, regex, s.clock)
+
+	s.entries[key] = entry
+	s.policy.Insert(entry)
+
+	return regex, evicted, nil
+}
+
+// evict removes entries chosen by the policy until the cache holds at most
+// limit entries, and returns the removed entries. The caller must hold the
+// lock.
+func (s *Store) evict(limit int) []*Entry {
+	var evicted []*Entry
+
+	for len(s.entries) > limit {
+		key, ok := s.policy.Victim()
+		if !ok {
+			break
+		}
+
+		if entry, ok := s.entries[key]; ok {
+			evicted = append(evicted, entry)
+		}
+
+		delete(s.entries, key)
+		s.policy.Remove(key)
+
+		s.counters.Evict(1)
+	}
+
+	return evicted
+}
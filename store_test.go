@@ -0,0 +1,314 @@
+package recache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+// fifo is a minimal first in, first out policy used to exercise the Store.
+type fifo struct {
+	keys []string
+}
+
+func (*fifo) Access(_ *recache.Entry) {}
+
+func (p *fifo) Insert(entry *recache.Entry) {
+	p.keys = append(p.keys, entry.Key())
+}
+
+func (p *fifo) Victim() (string, bool) {
+	if len(p.keys) == 0 {
+		return "", false
+	}
+
+	return p.keys[0], true
+}
+
+func (p *fifo) Remove(key string) {
+	for i, k := range p.keys {
+		if k == key {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+
+			return
+		}
+	}
+}
+
+func (*fifo) Resize(_ int) {}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx    = context.Background()
+		fp     = &fifo{}
+		store  = recache.NewStore(2, fp)
+		inputs = []string{`^a`, `^b`, `^c`}
+	)
+
+	for _, pattern := range inputs {
+		if _, err := store.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	if store.Size() != 2 {
+		t.Errorf("Size() = %d, want 2", store.Size())
+	}
+
+	want := []string{
+		recache.Key(`^b`, recache.DefaultFlag),
+		recache.Key(`^c`, recache.DefaultFlag),
+	}
+
+	if len(fp.keys) != len(want) || fp.keys[0] != want[0] || fp.keys[1] != want[1] {
+		t.Errorf("policy keys = %v, want %v", fp.keys, want)
+	}
+
+	stats := store.Stats()
+	if stats.Hits != 0 || stats.Misses != 3 || stats.Evictions != 1 || stats.CompileLatency.Count != 3 {
+		t.Errorf("Stats() = %+v, want 0 hits, 3 misses, 1 eviction, and 3 compiles", stats)
+	}
+
+	if _, err := store.Get(ctx, `^c`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get(%q) error = %v", `^c`, err)
+	}
+
+	if stats = store.Stats(); stats.Hits != 1 {
+		t.Errorf("Stats().Hits = %d, want 1", stats.Hits)
+	}
+
+	if err := store.SetCapacity(1); err != nil {
+		t.Fatalf("SetCapacity() error = %v", err)
+	}
+
+	if store.Size() != 1 || len(fp.keys) != 1 || fp.keys[0] != want[1] {
+		t.Errorf("SetCapacity() did not evict down to the new capacity: size %d, keys %v", store.Size(), fp.keys)
+	}
+
+	if err := store.SetCapacity(0); !errors.Is(err, recache.ErrInvalidCapacity) {
+		t.Errorf("SetCapacity(0) error = %v, want %v", err, recache.ErrInvalidCapacity)
+	}
+
+	if _, err := store.Get(ctx, `[`, recache.DefaultFlag); err == nil {
+		t.Errorf("Get() with an invalid pattern should return an error")
+	}
+
+	if stats = store.Stats(); stats.CompileErrors != 1 {
+		t.Errorf("Stats().CompileErrors = %d, want 1", stats.CompileErrors)
+	}
+
+	if !store.Delete(`^c`, recache.DefaultFlag) || store.Delete(`^c`, recache.DefaultFlag) {
+		t.Errorf("Delete() should report true for a stored pattern and false afterwards")
+	}
+
+	if store.Size() != 0 || len(fp.keys) != 0 {
+		t.Errorf("Delete() left size %d and keys %v", store.Size(), fp.keys)
+	}
+
+	if _, err := store.Get(ctx, `^d`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get(%q) error = %v", `^d`, err)
+	}
+
+	store.Clear()
+
+	if store.Size() != 0 || len(fp.keys) != 0 {
+		t.Errorf("Clear() left size %d and keys %v", store.Size(), fp.keys)
+	}
+}
+
+func TestStoreGetWithKey(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		store = recache.NewStore(4, &fifo{})
+	)
+
+	for _, key := range []string{"a", "b", "a"} {
+		if _, err := store.GetWithKey(ctx, key, `^x`, recache.DefaultFlag); err != nil {
+			t.Fatalf("GetWithKey(%q) error = %v", key, err)
+		}
+	}
+
+	if stats := store.Stats(); store.Size() != 2 || stats.Hits != 1 || stats.Misses != 2 {
+		t.Errorf("Size() = %d with %d hits, %d misses, want 2 with 1, 2", store.Size(), stats.Hits, stats.Misses)
+	}
+
+	for _, entry := range store.Entries() {
+		if entry.Pattern() != `^x` || (entry.Key() != "a" && entry.Key() != "b") {
+			t.Errorf("entry %q has pattern %q, want key a or b with pattern ^x", entry.Key(), entry.Pattern())
+		}
+	}
+}
+
+func TestStorePin(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		fp    = &fifo{}
+		store = recache.NewStore(2, fp)
+	)
+
+	if err := store.Pin(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	if store.Size() != 1 || len(fp.keys) != 0 {
+		t.Errorf("Pin() left size %d and policy keys %v, want 1 and none", store.Size(), fp.keys)
+	}
+
+	for _, pattern := range []string{`^a`, `^b`, `^c`, `^auth`} {
+		if _, err := store.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	if stats := store.Stats(); stats.Hits != 1 {
+		t.Errorf("pinned pattern was evicted: Stats().Hits = %d, want 1", stats.Hits)
+	}
+
+	if store.Size() != 2 {
+		t.Errorf("Size() = %d, want 2", store.Size())
+	}
+
+	if err := store.Pin(ctx, `^c`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Pin() of a cached pattern error = %v", err)
+	}
+
+	if err := store.Pin(ctx, `^d`, recache.DefaultFlag); !errors.Is(err, recache.ErrPinLimit) {
+		t.Errorf("Pin() beyond the capacity error = %v, want %v", err, recache.ErrPinLimit)
+	}
+
+	if err := store.Pin(ctx, `[`, recache.DefaultFlag); err == nil {
+		t.Errorf("Pin() with an invalid pattern should return an error")
+	}
+
+	// With the whole capacity pinned, new patterns are compiled but not cached.
+	if _, err := store.Get(ctx, `^e`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if store.Size() != 2 {
+		t.Errorf("Size() with the whole capacity pinned = %d, want 2", store.Size())
+	}
+
+	if err := store.SetCapacity(1); !errors.Is(err, recache.ErrPinLimit) {
+		t.Errorf("SetCapacity() below the pinned count error = %v, want %v", err, recache.ErrPinLimit)
+	}
+
+	if !store.Unpin(`^c`, recache.DefaultFlag) || store.Unpin(`^c`, recache.DefaultFlag) {
+		t.Errorf("Unpin() should report true for a pinned pattern and false afterwards")
+	}
+
+	if err := store.SetCapacity(1); err != nil {
+		t.Fatalf("SetCapacity() error = %v", err)
+	}
+
+	if store.Size() != 1 || len(fp.keys) != 0 {
+		t.Errorf("SetCapacity() left size %d and policy keys %v, want only the pinned entry", store.Size(), fp.keys)
+	}
+
+	store.Clear()
+
+	if err := store.Pin(ctx, `^d`, recache.DefaultFlag); err != nil {
+		t.Errorf("Pin() after Clear() error = %v", err)
+	}
+}
+
+func TestStoreFreeze(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		opts     []recache.FreezeOption
+		wantErr  error
+		wantSize int
+	}{
+		{
+			name:     "Compile misses",
+			wantSize: 2,
+		},
+		{
+			name:     "Error on misses",
+			opts:     []recache.FreezeOption{recache.WithMissMode(recache.MissError)},
+			wantErr:  recache.ErrNotFound,
+			wantSize: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := recache.NewStore(4, &fifo{})
+
+			if _, err := store.Get(ctx, `^a`, recache.DefaultFlag); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if err := store.Pin(ctx, `^auth`, recache.DefaultFlag); err != nil {
+				t.Fatalf("Pin() error = %v", err)
+			}
+
+			frozen := store.Freeze(tt.opts...)
+
+			for _, pattern := range []string{`^a`, `^auth`} {
+				if _, err := frozen.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+					t.Errorf("Get(%q) on the frozen cache error = %v", pattern, err)
+				}
+			}
+
+			// Frozen hits are not accesses to the store's entries.
+			for _, entry := range store.Entries() {
+				if entry.Frequency() != 0 {
+					t.Errorf("Frequency() of the store's %q entry = %d after frozen hits, want 0", entry.Pattern(), entry.Frequency())
+				}
+			}
+
+			_, err := frozen.Get(ctx, `^b`, recache.DefaultFlag)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Get() of a missing pattern error = %v, want %v", err, tt.wantErr)
+			}
+
+			if _, err = frozen.Get(ctx, `[`, recache.DefaultFlag); err == nil {
+				t.Errorf("Get() with an invalid pattern should return an error")
+			}
+
+			if frozen.Size() != tt.wantSize {
+				t.Errorf("Size() of the frozen cache = %d, want %d", frozen.Size(), tt.wantSize)
+			}
+
+			if stats := frozen.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+				t.Errorf("Stats() = %d hits, %d misses, want 2, 2", stats.Hits, stats.Misses)
+			}
+
+			if err = frozen.SetCapacity(8); !errors.Is(err, recache.ErrFrozen) {
+				t.Errorf("SetCapacity() error = %v, want %v", err, recache.ErrFrozen)
+			}
+
+			if frozen.Capacity() != 4 {
+				t.Errorf("Capacity() = %d, want 4", frozen.Capacity())
+			}
+
+			// The store and the frozen view do not affect each other.
+			if _, err = store.Get(ctx, `^c`, recache.DefaultFlag); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			frozen.Clear()
+
+			if frozen.Size() != 0 || store.Size() != 3 {
+				t.Errorf("Clear() left sizes %d and %d, want 0 and 3", frozen.Size(), store.Size())
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+// Package tenantre implements a thread-safe, multi-tenant cache for [Go's
+// standard regex package] that complies with the [recache.Cache] interface.
+//
+// Each tenant has a quota of entries that other tenants cannot evict, and all
+// tenants share the rest of the cache's capacity. When the cache is full, the
+// entry evicted is the least recently used one of the tenant furthest over
+// its quota, so one noisy tenant cannot flush everyone else's patterns.
+//
+// The cache is a [recache.Store] with a replacement policy that keeps track of
+// tenants. A tenant only exists while it holds entries, so callers passing
+// arbitrary tenant IDs cannot grow the cache's memory past its capacity.
+//
+// [recache.Store]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Store
+// [Go's standard regex package]: https://godocs.io/regexp
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+package tenantre
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+)
+
+// DefaultTenant is the tenant used when the context passed to Get does not
+// carry one.
+const DefaultTenant string = ""
+
+// _keySeparator separates the tenant from the pattern's key in namespaced
+// cache keys.
+const _keySeparator string = "/"
+
+// contextKey is the type of the context key holding the tenant.
+type contextKey struct{}
+
+// WithTenant returns a copy of the context carrying the given tenant, which
+// Get uses to pick the namespace to cache regular expressions in.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by the context, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(string)
+
+	return tenant, ok
+}
+
+// Key generates a cache key for the given tenant, pattern, and flag by
+// prefixing the key generated by [recache.Key] with the tenant.
+//
+// [recache.Key]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Key
+func Key(tenant, pattern string, flag recache.Flag) string {
+	return tenant + _keySeparator + recache.Key(pattern, flag)
+}
+
+// Cache is a thread-safe, multi-tenant regex cache.
+type Cache struct {
+	// store holds the entries of every tenant under namespaced keys.
+	store *recache.Store
+
+	// policy evicts entries fairly between tenants and keeps their state.
+	policy *fairPolicy
+}
+
+// Compile-time check to ensure Cache implements the recache.Cache,
+// recache.StatsReporter, and recache.EntryLister interfaces.
+var (
+	_ recache.Cache         = (*Cache)(nil)
+	_ recache.StatsReporter = (*Cache)(nil)
+	_ recache.EntryLister   = (*Cache)(nil)
+)
+
+// New returns a new multi-tenant cache with the given total capacity, where
+// each tenant is guaranteed up to quota entries, and options, such as
+// [recache.WithLogger] or [recache.WithClock].
+//
+// If capacity is less than 1, [recache.DefaultCapacity] is used instead. If
+// quota is less than 0, it is set to 0, so tenants only share the cache.
+//
+// [recache.WithLogger]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#WithLogger
+// [recache.WithClock]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#WithClock
+// [recache.DefaultCapacity]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#DefaultCapacity
+func New(capacity, quota int, opts ...recache.StoreOption) *Cache {
+	if quota < 0 {
+		quota = 0
+	}
+
+	p := newFairPolicy(quota)
+
+	return &Cache{
+		store:  recache.NewStore(capacity, p, opts...),
+		policy: p,
+	}
+}
+
+// Get returns a compiled regular expression from the namespace of the tenant
+// carried by the context, or [DefaultTenant] if there is none.
+func (c *Cache) Get(ctx context.Context, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
+	id, _ := TenantFromContext(ctx)
+
+	return c.GetTenant(ctx, id, pattern, flag)
+}
+
+// GetTenant returns a compiled regular expression from the namespace of the
+// given tenant. If the regular expression is not in the cache, it is compiled
+// and added to it.
+func (c *Cache) GetTenant(ctx context.Context, id, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
+	var obs recache.Observation
+
+	regex, err := c.store.GetWithKey(recache.WithObservation(ctx, &obs), Key(id, pattern, flag), pattern, flag)
+
+	// Pass the outcome on to whoever observes the caller's context.
+	recache.Observe(ctx, obs.Hit, obs.Compile)
+
+	c.policy.record(id, obs, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return regex, nil
+}
+
+// SetQuota sets the number of entries guaranteed to the given tenant,
+// overriding the cache's default quota. A negative quota restores the default.
+func (c *Cache) SetQuota(id string, quota int) {
+	c.policy.setQuota(id, quota)
+}
+
+// Tenants returns the IDs of the tenants currently holding entries, sorted.
+func (c *Cache) Tenants() []string {
+	return c.policy.ids()
+}
+
+// TenantSize returns the number of regular expressions the given tenant
+// currently holds.
+func (c *Cache) TenantSize(id string) int {
+	c.policy.mu.Lock()
+	defer c.policy.mu.Unlock()
+
+	if t, ok := c.policy.tenants[id]; ok {
+		return t.size
+	}
+
+	return 0
+}
+
+// TenantStats returns a snapshot of the activity of the given tenant since it
+// last started holding entries. Tenants that hold no entries have no stats, and
+// lookups that do not add an entry are only counted in Stats.
+func (c *Cache) TenantStats(id string) recache.Stats {
+	c.policy.mu.Lock()
+	t, ok := c.policy.tenants[id]
+	c.policy.mu.Unlock()
+
+	if !ok {
+		var counters recache.Counters
+
+		return counters.Stats()
+	}
+
+	return t.counters.Stats()
+}
+
+// Stats returns a snapshot of the activity of all tenants.
+func (c *Cache) Stats() recache.Stats {
+	return c.store.Stats()
+}
+
+// SetCapacity sets the maximum number of regular expressions that can be
+// stored in the cache, evicting entries fairly if the cache holds more than
+// that.
+func (c *Cache) SetCapacity(capacity int) error {
+	if err := c.store.SetCapacity(capacity); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// Capacity returns the maximum number of regular expressions that can be
+// stored in the cache.
+func (c *Cache) Capacity() int {
+	return c.store.Capacity()
+}
+
+// Size returns the number of regular expressions currently stored in the
+// cache, across all tenants.
+func (c *Cache) Size() int {
+	return c.store.Size()
+}
+
+// Entries returns a snapshot of the entries of every tenant, in no particular
+// order. Their keys are namespaced by tenant, as returned by Key.
+func (c *Cache) Entries() []*recache.Entry {
+	return c.store.Entries()
+}
+
+// Clear removes all regular expressions from the cache, for all tenants,
+// along with the tenants' stats. Quotas and the cache's stats are kept.
+func (c *Cache) Clear() {
+	c.store.Clear()
+}
+
+// tenant holds the state of a tenant holding entries.
+type tenant struct {
+	// policy tracks the tenant's entries in order of use.
+	policy *lrure.Policy
+
+	// counters keeps track of the tenant's activity.
+	counters recache.Counters
+
+	// size is the number of entries the tenant holds.
+	size int
+}
+
+// fairPolicy is the cache replacement policy evicting the least recently used
+// entry of the tenant furthest over its quota.
+type fairPolicy struct {
+	// tenants is a map of the IDs of the tenants holding entries to their
+	// state.
+	tenants map[string]*tenant
+
+	// owners is a map of keys to the ID of the tenant holding them.
+	owners map[string]string
+
+	// quotas is a map of tenant IDs to the quotas set for them with SetQuota.
+	quotas map[string]int
+
+	// victim is the key last returned by Victim, so Remove can tell
+	// evictions apart from other removals.
+	victim string
+
+	// capacity is the capacity of the cache.
+	capacity int
+
+	// quota is the default number of entries guaranteed to each tenant.
+	quota int
+
+	// mu protects the policy's state, which the cache reads outside the
+	// store's lock.
+	mu sync.Mutex
+}
+
+// Compile-time check to ensure fairPolicy implements the recache.Policy
+// interface.
+var _ recache.Policy = (*fairPolicy)(nil)
+
+// newFairPolicy returns a new fair policy with the given default quota.
+func newFairPolicy(quota int) *fairPolicy {
+	return &fairPolicy{
+		tenants: make(map[string]*tenant),
+		owners:  make(map[string]string),
+		quotas:  make(map[string]int),
+		quota:   quota,
+	}
+}
+
+// Access marks the given entry as the most recently used of its tenant.
+func (p *fairPolicy) Access(entry *recache.Entry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.tenants[p.owners[entry.Key()]]; ok {
+		t.policy.Access(entry)
+	}
+}
+
+// Insert adds the given entry as the most recently used of its tenant,
+// creating the tenant if it held no entries.
+func (p *fairPolicy) Insert(entry *recache.Entry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := tenantOf(entry)
+
+	t, ok := p.tenants[id]
+	if !ok {
+		t = &tenant{
+			policy: lrure.NewPolicy(),
+		}
+
+		p.tenants[id] = t
+	}
+
+	p.owners[entry.Key()] = id
+
+	t.policy.Insert(entry)
+	t.size++
+}
+
+// Victim returns the key of the least recently used entry of the tenant that
+// should lose an entry next.
+func (p *fairPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.victimTenant()
+	if t == nil {
+		return "", false
+	}
+
+	key, ok := t.policy.Victim()
+	if ok {
+		p.victim = key
+	}
+
+	return key, ok
+}
+
+// Remove stops tracking the entry with the given key, and drops its tenant if
+// it holds no other entries.
+func (p *fairPolicy) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id, ok := p.owners[key]
+	if !ok {
+		return
+	}
+
+	t := p.tenants[id]
+
+	t.policy.Remove(key)
+	t.size--
+
+	if key == p.victim {
+		t.counters.Evict(1)
+
+		p.victim = ""
+	}
+
+	delete(p.owners, key)
+
+	if t.size == 0 {
+		delete(p.tenants, id)
+	}
+}
+
+// Resize records the new capacity of the cache, which tenants within their
+// quota are ranked against.
+func (p *fairPolicy) Resize(capacity int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.capacity = capacity
+}
+
+// record adds the outcome of a lookup by the given tenant to the tenant's
+// stats, if it holds entries.
+func (p *fairPolicy) record(id string, obs recache.Observation, err error) {
+	p.mu.Lock()
+	t, ok := p.tenants[id]
+	p.mu.Unlock()
+
+	if !ok || !obs.Observed {
+		return
+	}
+
+	if obs.Hit {
+		t.counters.Hit()
+
+		return
+	}
+
+	t.counters.Miss()
+	t.counters.Compiled(obs.Compile, err)
+}
+
+// setQuota sets or, if quota is negative, removes the quota of a tenant.
+func (p *fairPolicy) setQuota(id string, quota int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if quota < 0 {
+		delete(p.quotas, id)
+
+		return
+	}
+
+	p.quotas[id] = quota
+}
+
+// ids returns the sorted IDs of the tenants holding entries.
+func (p *fairPolicy) ids() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ids := make([]string, 0, len(p.tenants))
+
+	for id := range p.tenants {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// victimTenant returns the tenant that should lose an entry next: the one
+// furthest over its quota, or the one holding the most entries if none is over
+// it. The caller must hold the lock.
+func (p *fairPolicy) victimTenant() *tenant {
+	var (
+		victim *tenant
+		best   int
+	)
+
+	for id, t := range p.tenants {
+		quota, ok := p.quotas[id]
+		if !ok {
+			quota = p.quota
+		}
+
+		// Rank tenants over their quota by how far over it they are, ahead of
+		// every tenant within its quota.
+		score := t.size - quota
+		if score <= 0 {
+			score = t.size - p.capacity
+		}
+
+		if victim == nil || score > best {
+			victim, best = t, score
+		}
+	}
+
+	return victim
+}
+
+// tenantOf returns the ID of the tenant an entry belongs to, which prefixes
+// its namespaced key.
+func tenantOf(entry *recache.Entry) string {
+	key := entry.Key()
+
+	return key[:len(key)-len(recache.Key(entry.Pattern(), entry.Flag()))-len(_keySeparator)]
+}
@@ -0,0 +1,225 @@
+package tenantre_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/tenantre"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Namespaces", func(t *testing.T) {
+		t.Parallel()
+
+		cache := tenantre.New(10, 2)
+
+		for _, id := range []string{"a", "b"} {
+			if _, err := cache.Get(tenantre.WithTenant(ctx, id), "a+", recache.DefaultFlag); err != nil {
+				t.Fatalf("Get(%q) error = %v", id, err)
+			}
+		}
+
+		if got := cache.Size(); got != 2 {
+			t.Errorf("Size() = %d, want 2", got)
+		}
+
+		if _, err := cache.GetTenant(ctx, "a", "a+", recache.DefaultFlag); err != nil {
+			t.Fatalf("GetTenant() error = %v", err)
+		}
+
+		if got := cache.TenantStats("a"); got.Hits != 1 || got.Misses != 1 {
+			t.Errorf("TenantStats(a) = %d hits, %d misses, want 1, 1", got.Hits, got.Misses)
+		}
+
+		if got := cache.TenantStats("b"); got.Hits != 0 || got.Misses != 1 {
+			t.Errorf("TenantStats(b) = %d hits, %d misses, want 0, 1", got.Hits, got.Misses)
+		}
+
+		if got := cache.Stats(); got.Hits != 1 || got.Misses != 2 {
+			t.Errorf("Stats() = %d hits, %d misses, want 1, 2", got.Hits, got.Misses)
+		}
+
+		if got, want := fmt.Sprint(cache.Tenants()), "[a b]"; got != want {
+			t.Errorf("Tenants() = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("Default tenant", func(t *testing.T) {
+		t.Parallel()
+
+		cache := tenantre.New(10, 2)
+
+		if _, err := cache.Get(ctx, "a+", recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		if got := cache.TenantSize(tenantre.DefaultTenant); got != 1 {
+			t.Errorf("TenantSize(DefaultTenant) = %d, want 1", got)
+		}
+	})
+
+	t.Run("Noisy tenant", func(t *testing.T) {
+		t.Parallel()
+
+		cache := tenantre.New(6, 2)
+
+		for i := 0; i < 2; i++ {
+			if _, err := cache.GetTenant(ctx, "quiet", fmt.Sprintf("q%d", i), recache.DefaultFlag); err != nil {
+				t.Fatalf("GetTenant() error = %v", err)
+			}
+		}
+
+		for i := 0; i < 100; i++ {
+			if _, err := cache.GetTenant(ctx, "noisy", fmt.Sprintf("n%d", i), recache.DefaultFlag); err != nil {
+				t.Fatalf("GetTenant() error = %v", err)
+			}
+		}
+
+		if got := cache.TenantSize("quiet"); got != 2 {
+			t.Errorf("TenantSize(quiet) = %d, want 2", got)
+		}
+
+		if got := cache.TenantSize("noisy"); got != 4 {
+			t.Errorf("TenantSize(noisy) = %d, want 4", got)
+		}
+
+		if got := cache.TenantStats("noisy").Evictions; got != 96 {
+			t.Errorf("TenantStats(noisy).Evictions = %d, want 96", got)
+		}
+
+		// The quiet tenant reclaims shared space from the noisy one.
+		if _, err := cache.GetTenant(ctx, "quiet", "q2", recache.DefaultFlag); err != nil {
+			t.Fatalf("GetTenant() error = %v", err)
+		}
+
+		if got := cache.TenantSize("quiet"); got != 3 {
+			t.Errorf("TenantSize(quiet) = %d, want 3", got)
+		}
+
+		if got := cache.TenantSize("noisy"); got != 3 {
+			t.Errorf("TenantSize(noisy) = %d, want 3", got)
+		}
+	})
+
+	t.Run("SetQuota", func(t *testing.T) {
+		t.Parallel()
+
+		cache := tenantre.New(4, 0)
+		cache.SetQuota("vip", 3)
+
+		for i := 0; i < 3; i++ {
+			if _, err := cache.GetTenant(ctx, "vip", fmt.Sprintf("v%d", i), recache.DefaultFlag); err != nil {
+				t.Fatalf("GetTenant() error = %v", err)
+			}
+		}
+
+		for i := 0; i < 10; i++ {
+			if _, err := cache.GetTenant(ctx, "other", fmt.Sprintf("o%d", i), recache.DefaultFlag); err != nil {
+				t.Fatalf("GetTenant() error = %v", err)
+			}
+		}
+
+		if got := cache.TenantSize("vip"); got != 3 {
+			t.Errorf("TenantSize(vip) = %d, want 3", got)
+		}
+	})
+
+	t.Run("Compile error", func(t *testing.T) {
+		t.Parallel()
+
+		cache := tenantre.New(4, 1)
+
+		if _, err := cache.GetTenant(ctx, "a", "(", recache.DefaultFlag); err == nil {
+			t.Fatal("GetTenant() error = nil, want error")
+		}
+
+		if got := cache.Stats().CompileErrors; got != 1 {
+			t.Errorf("Stats().CompileErrors = %d, want 1", got)
+		}
+
+		// Lookups that add no entry do not create the tenant.
+		if got := cache.Tenants(); len(got) != 0 {
+			t.Errorf("Tenants() = %v, want none", got)
+		}
+	})
+
+	t.Run("Tenants are bounded by the capacity", func(t *testing.T) {
+		t.Parallel()
+
+		cache := tenantre.New(4, 1)
+
+		for i := 0; i < 100; i++ {
+			if _, err := cache.GetTenant(ctx, fmt.Sprintf("t%d", i), "a+", recache.DefaultFlag); err != nil {
+				t.Fatalf("GetTenant() error = %v", err)
+			}
+		}
+
+		if got := len(cache.Tenants()); got != 4 {
+			t.Errorf("len(Tenants()) = %d, want 4", got)
+		}
+
+		if got := cache.TenantStats("t0"); got.Misses != 0 || got.Evictions != 0 {
+			t.Errorf("TenantStats() of an evicted tenant = %d misses, %d evictions, want 0, 0", got.Misses, got.Evictions)
+		}
+
+		cache.Clear()
+
+		if got := cache.Tenants(); len(got) != 0 {
+			t.Errorf("Tenants() after Clear() = %v, want none", got)
+		}
+	})
+
+	t.Run("Capacity", func(t *testing.T) {
+		t.Parallel()
+
+		cache := tenantre.New(4, 1)
+
+		for i := 0; i < 4; i++ {
+			if _, err := cache.GetTenant(ctx, "a", fmt.Sprintf("a%d", i), recache.DefaultFlag); err != nil {
+				t.Fatalf("GetTenant() error = %v", err)
+			}
+		}
+
+		if err := cache.SetCapacity(0); !errors.Is(err, recache.ErrInvalidCapacity) {
+			t.Errorf("SetCapacity(0) error = %v, want %v", err, recache.ErrInvalidCapacity)
+		}
+
+		if err := cache.SetCapacity(2); err != nil {
+			t.Fatalf("SetCapacity(2) error = %v", err)
+		}
+
+		if got := cache.Size(); got != 2 {
+			t.Errorf("Size() = %d, want 2", got)
+		}
+
+		cache.Clear()
+
+		if got := cache.Size(); got != 0 {
+			t.Errorf("Size() after Clear() = %d, want 0", got)
+		}
+
+		if got := cache.TenantSize("a"); got != 0 {
+			t.Errorf("TenantSize(a) after Clear() = %d, want 0", got)
+		}
+	})
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	recachetest.Run(t, func(capacity int) recache.Cache {
+		return tenantre.New(capacity, capacity/4)
+	})
+
+	recachetest.RunClock(t, func(capacity int, clock recache.Clock) recache.Cache {
+		return tenantre.New(capacity, capacity/4, recache.WithClock(clock))
+	})
+}
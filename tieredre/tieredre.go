@@ -0,0 +1,246 @@
+// Package tieredre implements a two-level cache for [Go's standard regex
+// package] that complies with the [recache.Cache] interface.
+//
+// A near-cache, one snapshot cache shared by every goroutine, sits in front
+// of any backing [recache.Cache]. The near-cache is striped by a hash of the
+// key into shards, each holding an immutable snapshot of its entries behind
+// an atomic pointer, so hits take no lock at all, which matters on paths
+// where even a read lock on a shared cache shows up in profiles. Striping
+// only spreads out the goroutines publishing new snapshots; any goroutine
+// looking up a key reads the same shard. Calls to Clear, Delete and
+// SetCapacity invalidate the whole near-cache.
+//
+// The near-cache keeps no timestamps of its own, so a clock for testing, such
+// as one given with [recache.WithClock], goes to the backing cache. Hits
+// served by the near-cache never reach the backing cache, so they are not
+// counted in its Stats and do not update the access times or frequencies its
+// eviction policy relies on.
+//
+// [recache.WithClock]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#WithClock
+// [Go's standard regex package]: https://godocs.io/regexp
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+package tieredre
+
+import (
+	"context"
+	"fmt"
+	"hash/maphash"
+	"math/bits"
+	"regexp"
+	"runtime"
+	"sync/atomic"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/internal/xregexp"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+)
+
+// DefaultNearCapacity is the default maximum number of regular expressions
+// each shard of the near-cache can hold.
+const DefaultNearCapacity int = 16
+
+// _cacheLineSize is the size shards are padded to, so that publishing a
+// snapshot in one shard does not slow down hits in its neighbours.
+const _cacheLineSize int = 64
+
+// Cache is a thread-safe two-level regex cache.
+type Cache struct {
+	// backing is the shared cache behind the near-cache.
+	backing recache.Cache
+
+	// shards holds the stripes of the near-cache. Its length is a power of
+	// two.
+	shards []shard
+
+	// seed seeds the hash picking the shard of a key.
+	seed maphash.Seed
+
+	// generation is incremented before and after the backing cache is
+	// cleared, resized or has an entry deleted, so it is odd while that
+	// happens. The near-cache only serves entries from the current
+	// generation.
+	generation atomic.Uint64
+
+	// nearCapacity is the maximum number of items each shard can hold.
+	nearCapacity int
+}
+
+// Compile-time check to ensure Cache implements the recache.Cache and
+// recache.Deleter interfaces.
+var (
+	_ recache.Cache   = (*Cache)(nil)
+	_ recache.Deleter = (*Cache)(nil)
+)
+
+// shard is a stripe of the near-cache, replaced as a whole whenever it
+// changes.
+type shard struct {
+	// near is the current snapshot of the shard, or nil if it is empty.
+	near atomic.Pointer[near]
+
+	_ [_cacheLineSize - 8]byte
+}
+
+// near is an immutable snapshot of a shard.
+type near struct {
+	// entries is a map of keys to compiled regular expressions that are never
+	// handed out directly.
+	entries map[string]*regexp.Regexp
+
+	// generation is the cache generation the entries belong to.
+	generation uint64
+}
+
+// New returns a new two-level cache in front of the given backing cache, with
+// a near-cache whose shards hold up to nearCapacity regular expressions each.
+//
+// If backing is nil, an [lrure.Cache] with the default capacity is used. If
+// nearCapacity is less than 1, [DefaultNearCapacity] is used instead.
+func New(backing recache.Cache, nearCapacity int) *Cache {
+	if backing == nil {
+		backing = lrure.New(recache.DefaultCapacity)
+	}
+
+	if nearCapacity < 1 {
+		nearCapacity = DefaultNearCapacity
+	}
+
+	// As many shards as goroutines can run at once, so that publishing
+	// snapshots rarely races, rounded up to a power of two so picking one
+	// is a mask.
+	shards := 1 << bits.Len(uint(runtime.GOMAXPROCS(0)-1))
+
+	return &Cache{
+		backing:      backing,
+		shards:       make([]shard, shards),
+		seed:         maphash.MakeSeed(),
+		nearCapacity: nearCapacity,
+	}
+}
+
+// Get returns a compiled regular expression from the shard of the near-cache
+// the pattern hashes to, falling back to the backing cache.
+func (c *Cache) Get(ctx context.Context, pattern string, flag recache.Flag) (*regexp.Regexp, error) {
+	var (
+		key        = recache.Key(pattern, flag)
+		s          = &c.shards[maphash.String(c.seed, key)&uint64(len(c.shards)-1)]
+		generation = c.generation.Load()
+		current    = s.near.Load()
+	)
+
+	if current != nil && current.generation == generation {
+		if regex, ok := current.entries[key]; ok {
+			recache.Observe(ctx, true, 0)
+
+			return xregexp.Copy(regex), nil
+		}
+	}
+
+	regex, err := c.backing.Get(ctx, pattern, flag)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	// The backing cache may have been cleared while it was read, so leave
+	// the near-cache alone.
+	if generation%2 == 1 {
+		return regex, nil
+	}
+
+	c.publish(s, current, generation, key, regex)
+
+	return regex, nil
+}
+
+// publish replaces the snapshot of a shard with one that also holds the given
+// regular expression, read from the backing cache at the given generation. If
+// the shard is full, one of its entries is evicted at random. If another
+// goroutine replaced the snapshot first, the regular expression is simply not
+// cached.
+func (c *Cache) publish(s *shard, current *near, generation uint64, key string, regex *regexp.Regexp) {
+	next := &near{
+		entries:    make(map[string]*regexp.Regexp, c.nearCapacity),
+		generation: generation,
+	}
+
+	// Entries from an older generation are dropped, and a snapshot from a
+	// newer one is left alone.
+	if current != nil && current.generation > generation {
+		return
+	}
+
+	if current != nil && current.generation == generation {
+		// Shards are small, so evicting at random is cheaper than keeping
+		// track of recency, and unlike dropping the whole shard it does not
+		// thrash when the working set is one entry larger than the shard.
+		// Map iteration starts at a random entry, so skipping the first one
+		// evicts at random.
+		skip := len(current.entries) >= c.nearCapacity
+
+		for k, v := range current.entries {
+			if skip {
+				skip = false
+
+				continue
+			}
+
+			next.entries[k] = v
+		}
+	}
+
+	next.entries[key] = xregexp.Copy(regex)
+
+	s.near.CompareAndSwap(current, next)
+}
+
+// SetCapacity sets the maximum number of regular expressions that can be
+// stored in the backing cache and invalidates the near-cache, so it does not
+// keep serving entries the backing cache evicted to shrink.
+func (c *Cache) SetCapacity(capacity int) error {
+	c.generation.Add(1)
+	defer c.generation.Add(1)
+
+	if err := c.backing.SetCapacity(capacity); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// Capacity returns the maximum number of regular expressions that can be
+// stored in the backing cache.
+func (c *Cache) Capacity() int {
+	return c.backing.Capacity()
+}
+
+// Size returns the number of regular expressions currently stored in the
+// backing cache.
+func (c *Cache) Size() int {
+	return c.backing.Size()
+}
+
+// Clear removes all regular expressions from the backing cache and
+// invalidates the near-cache.
+func (c *Cache) Clear() {
+	// Invalidate before and after clearing, so lookups that read the backing
+	// cache's old contents cannot refill the near-cache with them.
+	c.generation.Add(1)
+	defer c.generation.Add(1)
+
+	c.backing.Clear()
+}
+
+// Delete removes the regular expression compiled from the given pattern and
+// flag from the backing cache, if it supports it, and invalidates the
+// near-cache. It reports whether the backing cache held the regular
+// expression.
+func (c *Cache) Delete(pattern string, flag recache.Flag) bool {
+	c.generation.Add(1)
+	defer c.generation.Add(1)
+
+	if deleter, ok := c.backing.(recache.Deleter); ok {
+		return deleter.Delete(pattern, flag)
+	}
+
+	return false
+}
@@ -0,0 +1,19 @@
+package tieredre_test
+
+import (
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/tieredre"
+)
+
+// BenchmarkParallelHits compares lookups through the near-cache with
+// lookups that take the lock of the backing cache.
+func BenchmarkParallelHits(b *testing.B) {
+	recachetest.BenchmarkParallelHits(b,
+		recachetest.NamedCache{Name: "tieredre", Cache: tieredre.New(lrure.New(recache.DefaultCapacity), tieredre.DefaultNearCapacity)},
+		recachetest.NamedCache{Name: "lrure", Cache: lrure.New(recache.DefaultCapacity)},
+	)
+}
@@ -0,0 +1,251 @@
+package tieredre_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/tieredre"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		backing = lrure.New(2)
+		cache   = tieredre.New(backing, 1)
+	)
+
+	if cache.Capacity() != 2 {
+		t.Errorf("Capacity() = %d, want 2", cache.Capacity())
+	}
+
+	for i := 0; i < 3; i++ {
+		re, err := cache.Get(ctx, `a+?`, recache.DefaultFlag)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		if got := re.FindString("aaa"); got != "a" {
+			t.Fatalf("Longest() leaked between callers: FindString() = %q, want %q", got, "a")
+		}
+
+		re.Longest()
+	}
+
+	if _, err := cache.Get(ctx, `[`, recache.DefaultFlag); err == nil {
+		t.Errorf("Get() with an invalid pattern should return an error")
+	}
+
+	if cache.Size() != 1 {
+		t.Errorf("Size() = %d, want 1", cache.Size())
+	}
+
+	if !cache.Delete(`a+?`, recache.DefaultFlag) {
+		t.Errorf("Delete() = false, want true")
+	}
+
+	if cache.Size() != 0 {
+		t.Errorf("Size() after Delete() = %d, want 0", cache.Size())
+	}
+
+	// The near-cache was invalidated, so the pattern is loaded from the
+	// backing cache again.
+	if _, err := cache.Get(ctx, `a+?`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if backing.Size() != 1 {
+		t.Errorf("backing Size() after Get() = %d, want 1", backing.Size())
+	}
+
+	cache.Clear()
+
+	if _, err := cache.Get(ctx, `a+?`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// The first lookup, the invalid pattern, and one lookup after each of
+	// Delete() and Clear() invalidated the near-cache.
+	if stats := backing.Stats(); stats.Misses != 4 {
+		t.Errorf("backing misses = %d, want 4", stats.Misses)
+	}
+
+	if err := cache.SetCapacity(0); err == nil {
+		t.Errorf("SetCapacity(0) should return an error")
+	}
+}
+
+func TestCacheWorkingSetLargerThanNearCapacity(t *testing.T) {
+	t.Parallel()
+
+	const (
+		nearCapacity = 4
+		rounds       = 100
+	)
+
+	var (
+		ctx     = context.Background()
+		backing = lrure.New(recache.DefaultCapacity)
+		cache   = tieredre.New(backing, nearCapacity)
+	)
+
+	// Cycle through one pattern more than a shard can hold. Even if every
+	// pattern lands in the same shard, only the lookups of evicted patterns
+	// should reach the backing cache.
+	for i := 0; i < rounds*(nearCapacity+1); i++ {
+		pattern := fmt.Sprintf(`^%d$`, i%(nearCapacity+1))
+
+		if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	var (
+		stats    = backing.Stats()
+		lookups  = rounds * (nearCapacity + 1)
+		nearHits = lookups - int(stats.Hits+stats.Misses)
+	)
+
+	if nearHits < lookups/2 {
+		t.Errorf("near-cache served %d of %d lookups, want at least half", nearHits, lookups)
+	}
+}
+
+func TestCacheSetCapacity(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		backing = lrure.New(2)
+		cache   = tieredre.New(backing, 0)
+	)
+
+	for _, pattern := range []string{`^a`, `^b`} {
+		if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	if err := cache.SetCapacity(1); err != nil {
+		t.Fatalf("SetCapacity(1) error = %v", err)
+	}
+
+	// The backing cache evicted one of the patterns, so the near-cache must
+	// not keep serving both.
+	for _, pattern := range []string{`^a`, `^b`} {
+		if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get(%q) error = %v", pattern, err)
+		}
+	}
+
+	if stats := backing.Stats(); stats.Hits+stats.Misses != 4 {
+		t.Errorf("backing lookups = %d, want 4 as SetCapacity() invalidates the near-cache", stats.Hits+stats.Misses)
+	}
+}
+
+func TestCacheSurvivesGC(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		backing = lrure.New(recache.DefaultCapacity)
+		cache   = tieredre.New(backing, 0)
+	)
+
+	if _, err := cache.Get(ctx, `^a`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	runtime.GC()
+
+	for i := 0; i < 10; i++ {
+		if _, err := cache.Get(ctx, `^a`, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	if stats := backing.Stats(); stats.Hits != 0 || stats.Misses != 1 {
+		t.Errorf("backing Stats() = %d hits, %d misses, want 0, 1 as hits are served by the near-cache across GC", stats.Hits, stats.Misses)
+	}
+}
+
+func TestCacheClock(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx     = context.Background()
+		start   = time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+		clock   = recachetest.NewFakeClock(start)
+		backing = lrure.New(recache.DefaultCapacity, recache.WithClock(clock))
+		cache   = tieredre.New(backing, 0)
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get(ctx, `^a`, recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		clock.Advance(time.Minute)
+	}
+
+	entries := backing.Entries()
+
+	if len(entries) != 1 {
+		t.Fatalf("len(backing Entries()) = %d, want 1", len(entries))
+	}
+
+	// The second lookup was served by the near-cache.
+	if got := entries[0]; !got.Created().Equal(start) || !got.Accessed().Equal(start) {
+		t.Errorf("Created() = %v, Accessed() = %v, want %v for both", got.Created(), got.Accessed(), start)
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx      = context.Background()
+		cache    = tieredre.New(nil, 0)
+		patterns = []string{`^a`, `^b`, `^c`, `^d`}
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				pattern := patterns[(i+j)%len(patterns)]
+
+				re, err := cache.Get(ctx, pattern, recache.DefaultFlag)
+				if err != nil || re.String() != pattern {
+					t.Errorf("Get(%q) = %v, %v", pattern, re, err)
+				}
+
+				if j%25 == 0 {
+					cache.Clear()
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	recachetest.Run(t, func(capacity int) recache.Cache {
+		return tieredre.New(lrure.New(capacity), tieredre.DefaultNearCapacity)
+	})
+}
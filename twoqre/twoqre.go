@@ -0,0 +1,251 @@
+// Package twoqre implements a thread-safe cache for [Go's standard regex
+// package] that complies with the [recache.Cache] interface. It uses [2Q] as
+// its cache replacement policy.
+//
+// 2Q keeps patterns seen once in a small FIFO queue, A1in, and only promotes
+// them to the main LRU queue, Am, if they are looked up again shortly after
+// being evicted from A1in, which a ghost queue of recently evicted keys,
+// A1out, keeps track of. Scans that touch every pattern once only churn A1in,
+// so they do not flush the patterns in Am.
+//
+// [Go's standard regex package]: https://godocs.io/regexp
+// [recache.Cache]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#Cache
+// [2Q]: https://www.vldb.org/conf/1994/P439.PDF
+package twoqre
+
+import (
+	"container/list"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+)
+
+const (
+	// DefaultKin is the default size of the A1in queue, as a fraction of the
+	// capacity of the cache.
+	DefaultKin float64 = 0.25
+
+	// DefaultKout is the default size of the A1out ghost queue, as a fraction
+	// of the capacity of the cache.
+	DefaultKout float64 = 0.5
+)
+
+// Cache is a thread-safe regex cache using the 2Q policy.
+type Cache struct {
+	*recache.Store
+}
+
+// Compile-time check to ensure Cache implements the recache.Cache interface.
+var _ recache.Cache = (*Cache)(nil)
+
+// New returns a new 2Q cache with the given capacity and options, such as
+// [recache.WithLogger], using [DefaultKin] and [DefaultKout] as the sizes of
+// its queues.
+//
+// If capacity is less than 1, [recache.DefaultCapacity] is used instead.
+//
+// [recache.DefaultCapacity]: https://godocs.io/git.sr.ht/~jamesponddotco/recache-go#DefaultCapacity
+func New(capacity int, opts ...recache.StoreOption) *Cache {
+	return NewWithRatios(capacity, DefaultKin, DefaultKout, opts...)
+}
+
+// NewWithRatios is like New, but sizes the A1in and A1out queues as the given
+// fractions of the capacity of the cache. See [NewPolicy] for details.
+func NewWithRatios(capacity int, kin, kout float64, opts ...recache.StoreOption) *Cache {
+	return &Cache{
+		Store: recache.NewStore(capacity, NewPolicy(kin, kout), opts...),
+	}
+}
+
+// Policy is the 2Q cache replacement policy.
+type Policy struct {
+	// in is the A1in FIFO queue of entries seen once, newest first.
+	in *list.List
+
+	// out is the A1out ghost queue of the keys recently evicted from A1in,
+	// newest first.
+	out *list.List
+
+	// am is the Am LRU queue of entries seen again after leaving A1in, most
+	// recently used first.
+	am *list.List
+
+	// elements is a map of the keys of tracked entries to their elements in
+	// either A1in or Am.
+	elements map[string]*list.Element
+
+	// ghosts is a map of the keys in A1out to their elements.
+	ghosts map[string]*list.Element
+
+	// victim is the key last returned by Victim, which is moved to A1out if it
+	// is removed from A1in.
+	victim string
+
+	// kinRatio and koutRatio are the sizes of A1in and A1out as fractions of
+	// the capacity of the cache.
+	kinRatio  float64
+	koutRatio float64
+
+	// kin is the number of entries A1in holds before it is evicted from.
+	kin int
+
+	// kout is the maximum number of keys A1out holds.
+	kout int
+}
+
+// Compile-time check to ensure Policy implements the recache.Policy interface.
+var _ recache.Policy = (*Policy)(nil)
+
+// NewPolicy returns a new 2Q cache replacement policy. A1in is allowed to grow
+// to kin times the capacity of the cache before it is evicted from, and A1out
+// remembers the keys of up to kout times the capacity of the cache.
+//
+// The paper recommends 0.25 and 0.5. Ratios outside of (0, 1] for kin, or
+// less than or equal to 0 for kout, are replaced with [DefaultKin] and
+// [DefaultKout].
+func NewPolicy(kin, kout float64) *Policy {
+	if kin <= 0 || kin > 1 {
+		kin = DefaultKin
+	}
+
+	if kout <= 0 {
+		kout = DefaultKout
+	}
+
+	p := &Policy{
+		in:        list.New(),
+		out:       list.New(),
+		am:        list.New(),
+		elements:  make(map[string]*list.Element),
+		ghosts:    make(map[string]*list.Element),
+		kinRatio:  kin,
+		koutRatio: kout,
+	}
+
+	p.Resize(recache.DefaultCapacity)
+
+	return p
+}
+
+// Access marks the given entry as the most recently used if it is in Am.
+// Entries in A1in are not moved, as repeated lookups shortly after the first
+// are usually correlated and say nothing about long-term popularity.
+func (p *Policy) Access(entry *recache.Entry) {
+	elem, ok := p.elements[entry.Key()]
+	if !ok {
+		return
+	}
+
+	if queue, ok := elem.Value.(queued); ok && queue.am {
+		p.am.MoveToFront(elem)
+	}
+}
+
+// Insert adds the given entry to Am if its key is in A1out, and to A1in
+// otherwise.
+func (p *Policy) Insert(entry *recache.Entry) {
+	key := entry.Key()
+
+	if ghost, ok := p.ghosts[key]; ok {
+		p.out.Remove(ghost)
+		delete(p.ghosts, key)
+
+		p.elements[key] = p.am.PushFront(queued{key: key, am: true})
+
+		return
+	}
+
+	p.elements[key] = p.in.PushFront(queued{key: key})
+}
+
+// Victim returns the key of the oldest entry in A1in if A1in holds more than
+// its share of the cache, and of the least recently used entry in Am
+// otherwise.
+func (p *Policy) Victim() (string, bool) {
+	var elem *list.Element
+
+	switch {
+	case p.in.Len() > p.kin || p.am.Len() == 0:
+		elem = p.in.Back()
+	default:
+		elem = p.am.Back()
+	}
+
+	if elem == nil {
+		return "", false
+	}
+
+	queue, ok := elem.Value.(queued)
+	if !ok {
+		return "", false
+	}
+
+	p.victim = queue.key
+
+	return queue.key, true
+}
+
+// Remove stops tracking the entry with the given key. If the entry was in
+// A1in and was the last victim, its key is remembered in A1out.
+func (p *Policy) Remove(key string) {
+	victim := p.victim
+	p.victim = ""
+
+	elem, ok := p.elements[key]
+	if !ok {
+		return
+	}
+
+	delete(p.elements, key)
+
+	if queue, ok := elem.Value.(queued); ok && queue.am {
+		p.am.Remove(elem)
+
+		return
+	}
+
+	p.in.Remove(elem)
+
+	if key == victim {
+		p.ghosts[key] = p.out.PushFront(key)
+		p.trimGhosts()
+	}
+}
+
+// Resize sizes A1in and A1out for the new capacity of the cache.
+func (p *Policy) Resize(capacity int) {
+	p.kin = atLeastOne(float64(capacity) * p.kinRatio)
+	p.kout = atLeastOne(float64(capacity) * p.koutRatio)
+
+	p.trimGhosts()
+}
+
+// trimGhosts forgets the oldest keys in A1out until it fits its size.
+func (p *Policy) trimGhosts() {
+	for p.out.Len() > p.kout {
+		elem := p.out.Back()
+
+		if key, ok := elem.Value.(string); ok {
+			delete(p.ghosts, key)
+		}
+
+		p.out.Remove(elem)
+	}
+}
+
+// queued is the value of the elements of A1in and Am.
+type queued struct {
+	// key is the key of the entry.
+	key string
+
+	// am reports whether the element is in Am rather than A1in.
+	am bool
+}
+
+// atLeastOne returns n rounded down, or 1 if that is less than 1.
+func atLeastOne(n float64) int {
+	if n < 1 {
+		return 1
+	}
+
+	return int(n)
+}
@@ -0,0 +1,232 @@
+package twoqre_test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"git.sr.ht/~jamesponddotco/recache-go"
+	"git.sr.ht/~jamesponddotco/recache-go/lrure"
+	"git.sr.ht/~jamesponddotco/recache-go/recachetest"
+	"git.sr.ht/~jamesponddotco/recache-go/twoqre"
+)
+
+func newEntry(key string) *recache.Entry {
+	return recache.NewEntry(key, key, regexp.MustCompile(regexp.QuoteMeta(key)))
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		p := twoqre.NewPolicy(twoqre.DefaultKin, twoqre.DefaultKout)
+
+		if _, ok := p.Victim(); ok {
+			t.Errorf("Victim() on an empty policy should return false")
+		}
+	})
+
+	t.Run("A1in is FIFO", func(t *testing.T) {
+		t.Parallel()
+
+		p := twoqre.NewPolicy(twoqre.DefaultKin, twoqre.DefaultKout)
+		p.Resize(4)
+
+		p.Insert(newEntry("a"))
+		p.Insert(newEntry("b"))
+
+		// Lookups in A1in do not change the order of eviction.
+		p.Access(newEntry("a"))
+
+		if key, _ := p.Victim(); key != "a" {
+			t.Errorf("Victim() = %q, want %q", key, "a")
+		}
+	})
+
+	t.Run("Ghost hit promotes to Am", func(t *testing.T) {
+		t.Parallel()
+
+		p := twoqre.NewPolicy(twoqre.DefaultKin, twoqre.DefaultKout)
+		p.Resize(4)
+
+		for _, key := range []string{"a", "b", "c"} {
+			p.Insert(newEntry(key))
+		}
+
+		key, _ := p.Victim()
+		p.Remove(key)
+
+		if key != "a" {
+			t.Fatalf("Victim() = %q, want %q", key, "a")
+		}
+
+		// The evicted key comes back and goes straight to Am. A1in is evicted
+		// from while it is over its share of one entry, and Am after that.
+		p.Insert(newEntry("a"))
+
+		for _, want := range []string{"b", "a", "c"} {
+			key, _ := p.Victim()
+			p.Remove(key)
+
+			if key != want {
+				t.Errorf("Victim() = %q, want %q", key, want)
+			}
+		}
+	})
+
+	t.Run("Remove without eviction leaves no ghost", func(t *testing.T) {
+		t.Parallel()
+
+		p := twoqre.NewPolicy(twoqre.DefaultKin, twoqre.DefaultKout)
+		p.Resize(4)
+
+		p.Insert(newEntry("a"))
+		p.Insert(newEntry("b"))
+		p.Remove("a")
+		p.Insert(newEntry("a"))
+
+		// Had "a" been remembered as a ghost, it would be in Am and "b" would
+		// be the only entry in A1in.
+		if key, _ := p.Victim(); key != "b" {
+			t.Errorf("Victim() = %q, want %q", key, "b")
+		}
+	})
+
+	t.Run("Am is LRU", func(t *testing.T) {
+		t.Parallel()
+
+		p := twoqre.NewPolicy(twoqre.DefaultKin, 1)
+		p.Resize(4)
+
+		for _, key := range []string{"a", "b", "c", "d"} {
+			p.Insert(newEntry(key))
+		}
+
+		// Evict everything so the keys end up in A1out, then bring them back
+		// into Am.
+		for i := 0; i < 4; i++ {
+			key, _ := p.Victim()
+			p.Remove(key)
+		}
+
+		for _, key := range []string{"a", "b", "c"} {
+			p.Insert(newEntry(key))
+		}
+
+		p.Access(newEntry("a"))
+
+		for _, want := range []string{"b", "c", "a"} {
+			key, _ := p.Victim()
+			p.Remove(key)
+
+			if key != want {
+				t.Errorf("Victim() = %q, want %q", key, want)
+			}
+		}
+	})
+}
+
+func TestCacheScanResistance(t *testing.T) {
+	t.Parallel()
+
+	const capacity = 10
+
+	// run looks up a hot set of patterns enough for it to settle in the cache,
+	// then scans many patterns once, and returns the number of hits on the hot
+	// set afterwards.
+	run := func(t *testing.T, cache statsCache) int {
+		t.Helper()
+
+		ctx := context.Background()
+
+		get := func(pattern string) {
+			t.Helper()
+
+			if _, err := cache.Get(ctx, pattern, recache.DefaultFlag); err != nil {
+				t.Fatalf("Get(%q) error = %v", pattern, err)
+			}
+		}
+
+		hot := []string{`^h0$`, `^h1$`, `^h2$`}
+
+		for round := 0; round < 3; round++ {
+			for _, pattern := range hot {
+				get(pattern)
+			}
+
+			for i := 0; i < capacity; i++ {
+				get(fmt.Sprintf(`^warm%d-%d$`, round, i))
+			}
+		}
+
+		for i := 0; i < capacity*10; i++ {
+			get(fmt.Sprintf(`^scan%d$`, i))
+		}
+
+		hits := 0
+
+		for _, pattern := range hot {
+			before := cache.Stats().Hits
+
+			get(pattern)
+
+			if cache.Stats().Hits > before {
+				hits++
+			}
+		}
+
+		return hits
+	}
+
+	if hits := run(t, twoqre.New(capacity)); hits != 3 {
+		t.Errorf("2Q served %d of 3 hot patterns after a scan, want 3", hits)
+	}
+
+	if hits := run(t, lrure.New(capacity)); hits != 0 {
+		t.Errorf("LRU served %d of 3 hot patterns after a scan, want 0", hits)
+	}
+}
+
+// statsCache is a cache that reports its activity.
+type statsCache interface {
+	recache.Cache
+	recache.StatsReporter
+}
+
+func TestCachePin(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx   = context.Background()
+		cache = twoqre.New(2)
+	)
+
+	if err := cache.Pin(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := cache.Get(ctx, fmt.Sprintf(`^p%d`, i), recache.DefaultFlag); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+	}
+
+	if _, err := cache.Get(ctx, `^auth`, recache.DefaultFlag); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Errorf("Stats().Hits = %d, want 1 with the pinned pattern still cached", stats.Hits)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	recachetest.Run(t, func(capacity int) recache.Cache {
+		return twoqre.New(capacity)
+	})
+}